@@ -0,0 +1,103 @@
+// simulate 在本地让机器人策略反复自我对局，输出各角色的胜率、平均倍数、炸弹频率和对局长度，
+// 用于衡量机器人的改动是否真的更强，以及调整玩法的平衡性。
+//
+// 用法示例：
+//
+//	go run ./cmd/simulate -games 2000 -rules classic -robots hard,normal,normal
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+	"landlord/service"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	games         = flag.Int("games", 1000, "number of games to simulate")
+	rules         = flag.String("rules", common.ClassicRules{}.Name(), "rule set: classic, no_kicker_bomb or two_deck")
+	robots        = flag.String("robots", "normal,normal,normal", "comma separated strategy of each seat: easy, normal, hard or expert")
+	expertSamples = flag.Int("expert-samples", 100, "samples per play of the expert robot")
+	expertTimeout = flag.Duration("expert-timeout", 200*time.Millisecond, "thinking time per play of the expert robot")
+	seed          = flag.Int64("seed", 0, "seed of the first deal, 0 for random deals; expert robots are never reproducible")
+)
+
+func main() {
+	flag.Parse()
+	logs.SetLevel(logs.LevelError)
+	if *seed != 0 {
+		// 普通机器人叫分时的随机误差也需要固定下来
+		rand.Seed(*seed)
+	}
+
+	ruleSet, ok := common.RuleSets[*rules]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown rule set %q\n", *rules)
+		os.Exit(2)
+	}
+	names := strings.Split(*robots, ",")
+	strategies := make([]service.RobotStrategy, 0, len(names))
+	for _, name := range names {
+		strategy, err := newStrategy(strings.TrimSpace(name))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		strategies = append(strategies, strategy)
+	}
+
+	sim := &service.Simulation{Rules: ruleSet, Strategies: strategies, Seed: *seed}
+	start := time.Now()
+	stats, err := sim.Run(*games)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	report(names, stats, time.Since(start))
+}
+
+// newStrategy 返回名称对应的机器人策略。
+func newStrategy(name string) (service.RobotStrategy, error) {
+	switch name {
+	case "easy":
+		return service.EasyStrategy{}, nil
+	case "normal":
+		return service.NormalStrategy{}, nil
+	case "hard":
+		return service.HardStrategy{}, nil
+	case "expert":
+		return service.ExpertStrategy{Samples: *expertSamples, Timeout: *expertTimeout}, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q", name)
+}
+
+// report 输出统计结果。
+func report(names []string, stats service.SimulationStats, elapsed time.Duration) {
+	games := float64(stats.Games)
+	fmt.Printf("games: %d (%v)\n", stats.Games, elapsed.Round(time.Millisecond))
+	fmt.Printf("landlord win rate: %.1f%%\n", percent(stats.LandlordWins, stats.Games))
+	fmt.Printf("average call score: %.2f\n", float64(stats.CallScore)/games)
+	fmt.Printf("average multiple: %.2f\n", float64(stats.Multiple)/games)
+	fmt.Printf("bombs per game: %.2f, games with bombs: %.1f%%\n", float64(stats.Bombs)/games, percent(stats.BombGames, stats.Games))
+	fmt.Printf("shots per game: %.1f\n", float64(stats.Shots)/games)
+	fmt.Println()
+	fmt.Printf("%-6s %-8s %18s %18s\n", "seat", "robot", "as landlord", "as farmer")
+	for i, seat := range stats.Seats {
+		fmt.Printf("%-6d %-8s %6d/%-5d %5.1f%% %6d/%-5d %5.1f%%\n", i, strings.TrimSpace(names[i]),
+			seat.LandlordWins, seat.LandlordGames, percent(seat.LandlordWins, seat.LandlordGames),
+			seat.FarmerWins, seat.FarmerGames, percent(seat.FarmerWins, seat.FarmerGames))
+	}
+}
+
+// percent 返回 n 占 total 的百分比，total 为 0 时返回 0。
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
@@ -0,0 +1,169 @@
+package common
+
+const (
+	// groupCost 是拆牌时每一手牌的代价。
+	groupCost = 100
+
+	// bombKickerCost 是把炸弹拆成四带二出掉的额外代价，炸弹应尽量留着压牌。
+	bombKickerCost = 80
+)
+
+// decomposeStep 记录某种点数分布下最优拆法的代价和第一手牌的点数。
+type decomposeStep struct {
+	cost  int
+	ranks []int
+}
+
+// Decompose 按玩法 rules 把手牌 hand 拆成出完所需手数最少的一组牌型，返回每一手牌的编号。
+// 手数相同时尽量不把炸弹拆成四带二，并优先带小牌；带牌只带最小的散牌，因此结果是近似最优的。
+// 拆出的牌型按从小到大的顺序排列，与 LegalMovesWith 的顺序一致。
+func Decompose(rules RuleSet, hand []int) (groups [][]int) {
+	var counts [rankCount]int
+	for _, card := range hand {
+		counts[CardRank(card)]++
+	}
+	memo := make(map[[rankCount]int]decomposeStep)
+	decomposeCounts(rules, counts, memo)
+
+	remaining := append([]int(nil), hand...)
+	for counts != ([rankCount]int{}) {
+		step := memo[counts]
+		cards := pokersInHand(remaining, ranksString(step.ranks))
+		groups = append(groups, cards)
+		remaining = removeCards(remaining, cards)
+		for _, rank := range step.ranks {
+			counts[rank]--
+		}
+	}
+	sortMoves(rules, groups)
+	return
+}
+
+// Turns 返回按最优拆法出完手牌 hand 需要的手数。
+func Turns(rules RuleSet, hand []int) int {
+	return len(Decompose(rules, hand))
+}
+
+// decomposeCounts 计算点数分布 counts 的最优拆法代价，结果记录在 memo 中。
+// 每次只考虑包含最小点数的牌型，因为最小的牌总要属于某一手牌，这样不会漏掉最优解。
+func decomposeCounts(rules RuleSet, counts [rankCount]int, memo map[[rankCount]int]decomposeStep) int {
+	if counts == ([rankCount]int{}) {
+		return 0
+	}
+	if step, ok := memo[counts]; ok {
+		return step.cost
+	}
+	lowest := 0
+	for counts[lowest] == 0 {
+		lowest++
+	}
+	best := decomposeStep{cost: -1}
+	for _, ranks := range decomposeCandidates(counts) {
+		if !containsInt(ranks, lowest) {
+			continue
+		}
+		hand, err := rules.Classify(ranksToCards(ranks))
+		if err != nil {
+			continue
+		}
+		cost := groupCost
+		if hand.Kind == KindBombSingle || hand.Kind == KindBombPair {
+			cost += bombKickerCost
+		}
+		// 手数相同时优先带小牌，把大牌留下来控场
+		for _, kicker := range hand.Kickers {
+			cost += kicker
+		}
+		rest := counts
+		for _, rank := range ranks {
+			rest[rank]--
+		}
+		cost += decomposeCounts(rules, rest, memo)
+		if best.cost < 0 || cost < best.cost {
+			best = decomposeStep{cost: cost, ranks: ranks}
+		}
+	}
+	memo[counts] = best
+	return best.cost
+}
+
+// decomposeCandidates 列出拆牌时需要尝试的点数组合。
+// 不带牌的牌型全部列出；带牌的牌型只带最小的几张散牌（该点数恰好是一张或一对），
+// 不拆开三张、炸弹去当带牌，避免组合数爆炸。
+func decomposeCandidates(counts [rankCount]int) (res [][]int) {
+	for _, kind := range []HandKind{KindSingle, KindPair, KindTrio, KindBomb, KindRocket, KindSeqSingle, KindSeqPair, KindSeqTrio} {
+		res = append(res, rankCandidates(counts, kind, 0)...)
+	}
+	bodies := append(rankCandidates(counts, KindTrio, 0), rankCandidates(counts, KindSeqTrio, 0)...)
+	for _, bomb := range rankCandidates(counts, KindBomb, 0) {
+		if len(bomb) == 4 {
+			bodies = append(bodies, bomb)
+		}
+	}
+	for _, body := range bodies {
+		need := len(body) / 3
+		if len(body) == 4 {
+			need = 2
+		}
+		for width := 1; width <= 2; width++ {
+			var kickers []int
+			for rank, count := range counts {
+				if count == width && !containsInt(body, rank) && len(kickers) < need {
+					kickers = append(kickers, rank)
+				}
+			}
+			if len(kickers) < need {
+				continue
+			}
+			ranks := append([]int(nil), body...)
+			for _, kicker := range kickers {
+				ranks = append(ranks, repeatRank(kicker, width)...)
+			}
+			res = append(res, ranks)
+		}
+	}
+	return
+}
+
+// ranksToCards 为点数列表构造一组互不重复的牌编号，用于只关心点数的牌型判断。
+// 同一点数的第 k 张依次取不同花色，超过四张时取第二副牌。
+func ranksToCards(ranks []int) []int {
+	var used [rankCount]int
+	cards := make([]int, 0, len(ranks))
+	for _, rank := range ranks {
+		k := used[rank]
+		used[rank]++
+		var card int
+		switch rank {
+		case RankRedJoker:
+			card = 52 + 54*k
+		case RankBlackJoker:
+			card = 53 + 54*k
+		default:
+			card = (rank+2)%13 + 13*(k%4) + 54*(k/4)
+		}
+		cards = append(cards, card)
+	}
+	return cards
+}
+
+// containsInt 判断 list 中是否包含 v。
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// removeCards 返回从 hand 中去掉 cards 后剩下的牌，不修改 hand。
+func removeCards(hand, cards []int) []int {
+	res := make([]int, 0, len(hand))
+	for _, card := range hand {
+		if !containsInt(cards, card) {
+			res = append(res, card)
+		}
+	}
+	return res
+}
@@ -0,0 +1,198 @@
+package common
+
+import (
+	"errors"
+	"strconv"
+)
+
+// HandKind 表示牌型的种类，取值与 rule.json 中的牌型名称一致（连牌不含长度后缀）。
+type HandKind string
+
+const (
+	KindSingle        HandKind = "single"
+	KindPair          HandKind = "pair"
+	KindTrio          HandKind = "trio"
+	KindBomb          HandKind = "bomb"
+	KindRocket        HandKind = "rocket"
+	KindTrioSingle    HandKind = "trio_single"
+	KindTrioPair      HandKind = "trio_pair"
+	KindSeqSingle     HandKind = "seq_single"
+	KindSeqPair       HandKind = "seq_pair"
+	KindSeqTrio       HandKind = "seq_trio"
+	KindSeqTrioSingle HandKind = "seq_trio_single"
+	KindSeqTrioPair   HandKind = "seq_trio_pair"
+	KindBombSingle    HandKind = "bomb_single"
+	KindBombPair      HandKind = "bomb_pair"
+)
+
+const (
+	// RankA 是 A 的点数，顺子、连对和飞机最大只能连到 A。
+	RankA = 11
+
+	// Rank2 是 2 的点数。
+	Rank2 = 12
+
+	// RankBlackJoker 是小王的点数。
+	RankBlackJoker = 13
+
+	// RankRedJoker 是大王的点数。
+	RankRedJoker = 14
+
+	// rankCount 是点数的种类数：3~2 共 13 种，加上大小王。
+	rankCount = 15
+)
+
+var (
+	// ErrEmptyHand 表示没有出任何牌。
+	ErrEmptyHand = errors.New("empty hand")
+
+	// ErrInvalidCard 表示牌的编号不合法或者重复。
+	ErrInvalidCard = errors.New("invalid card")
+
+	// ErrInvalidHand 表示这组牌不能构成任何牌型。
+	ErrInvalidHand = errors.New("invalid hand")
+)
+
+// Hand 是一手牌经过分析后的牌型。
+//
+// Kind 是牌型种类。
+//
+// Rank 是牌型的主点数（按 3 最小、大王最大排列，见 CardRank）：
+// 单、对、三、炸弹为该张牌的点数，带牌的牌型为主体（三张或四张）的点数，
+// 顺子、连对和飞机为最小一组的点数。同种类同长度的牌型按 Rank 比较大小。
+//
+// Length 是连牌的组数（例如 seq_single5 为 5、飞机 333444 为 2），
+// 炸弹和王炸为张数，其他牌型为 1。
+//
+// Kickers 是带牌的点数，带对子时每对只记一次，按点数升序排列。
+type Hand struct {
+	Kind    HandKind
+	Rank    int
+	Length  int
+	Kickers []int
+}
+
+// Type 返回与 rule.json 一致的牌型名称，连牌会带上长度后缀，例如 "seq_pair3"。
+func (h Hand) Type() string {
+	switch h.Kind {
+	case KindSeqSingle, KindSeqPair, KindSeqTrio, KindSeqTrioSingle, KindSeqTrioPair:
+		return string(h.Kind) + strconv.Itoa(h.Length)
+	}
+	return string(h.Kind)
+}
+
+// IsBomb 判断牌型是否为炸弹或王炸，出这类牌会使倍数翻倍。
+func (h Hand) IsBomb() bool {
+	return h.Kind == KindBomb || h.Kind == KindRocket
+}
+
+// CardRank 返回牌编号对应的点数，3 为 0、A 为 11、2 为 12、小王为 13、大王为 14。
+// 编号 52 为大王、53 为小王，其余编号按 "A234567890JQK" 的顺序对 13 取模。
+// 两副牌时第二副牌的编号为 54~107，按对 54 取模后的编号计算。
+func CardRank(card int) int {
+	card %= 54
+	switch card {
+	case 52:
+		return RankRedJoker
+	case 53:
+		return RankBlackJoker
+	}
+	return (card%13 + 11) % 13
+}
+
+// Classify 根据点数分布分析一手牌的牌型。
+//
+// 输入参数 cards 是牌的编号，顺序不限。
+// 如果 cards 为空返回 ErrEmptyHand，包含非法或重复的编号返回 ErrInvalidCard，
+// 不能构成任何牌型返回 ErrInvalidHand。
+//
+// 对 rule.json 中的每一手牌，Classify 返回的 Type() 与其牌型名称一致，
+// 同时也能识别规则表没有生成的牌型，例如 seq_trio_single6。
+func Classify(cards []int) (Hand, error) {
+	counts, err := countRanks(cards, 54)
+	if err != nil {
+		return Hand{}, err
+	}
+	return classifyCounts(counts, len(cards))
+}
+
+// countRanks 统计每个点数的张数，牌的编号必须在 [0, deckSize) 范围内且不能重复。
+func countRanks(cards []int, deckSize int) (counts [rankCount]int, err error) {
+	if len(cards) == 0 {
+		err = ErrEmptyHand
+		return
+	}
+	seen := make(map[int]bool, len(cards))
+	for _, card := range cards {
+		if card < 0 || card >= deckSize || seen[card] {
+			err = ErrInvalidCard
+			return
+		}
+		seen[card] = true
+		counts[CardRank(card)]++
+	}
+	return
+}
+
+// classifyCounts 根据每个点数的张数判断牌型，total 为总张数。
+func classifyCounts(counts [rankCount]int, total int) (Hand, error) {
+	// groups[n] 为恰好有 n 张的点数，按点数升序排列
+	var groups [5][]int
+	for rank, count := range counts {
+		if count > 4 {
+			return Hand{}, ErrInvalidHand
+		}
+		if count > 0 {
+			groups[count] = append(groups[count], rank)
+		}
+	}
+	singles, pairs, trios, bombs := groups[1], groups[2], groups[3], groups[4]
+
+	switch {
+	case total == 1:
+		return Hand{Kind: KindSingle, Rank: singles[0], Length: 1}, nil
+	case total == 2 && len(singles) == 2 && singles[0] == RankBlackJoker:
+		return Hand{Kind: KindRocket, Rank: RankRedJoker, Length: 2}, nil
+	case total == 2 && len(pairs) == 1:
+		return Hand{Kind: KindPair, Rank: pairs[0], Length: 1}, nil
+	case total == 3 && len(trios) == 1:
+		return Hand{Kind: KindTrio, Rank: trios[0], Length: 1}, nil
+	case total == 4 && len(bombs) == 1:
+		return Hand{Kind: KindBomb, Rank: bombs[0], Length: 4}, nil
+	case total == 4 && len(trios) == 1:
+		return Hand{Kind: KindTrioSingle, Rank: trios[0], Length: 1, Kickers: singles}, nil
+	case total == 5 && len(trios) == 1 && len(pairs) == 1:
+		return Hand{Kind: KindTrioPair, Rank: trios[0], Length: 1, Kickers: pairs}, nil
+	case total == 6 && len(bombs) == 1 && len(singles) == 2:
+		return Hand{Kind: KindBombSingle, Rank: bombs[0], Length: 1, Kickers: singles}, nil
+	case total == 8 && len(bombs) == 1 && len(pairs) == 2:
+		return Hand{Kind: KindBombPair, Rank: bombs[0], Length: 1, Kickers: pairs}, nil
+	}
+
+	switch {
+	case total >= 5 && len(singles) == total && isChain(singles):
+		return Hand{Kind: KindSeqSingle, Rank: singles[0], Length: len(singles)}, nil
+	case total >= 6 && len(pairs)*2 == total && len(pairs) >= 3 && isChain(pairs):
+		return Hand{Kind: KindSeqPair, Rank: pairs[0], Length: len(pairs)}, nil
+	case total >= 6 && len(trios)*3 == total && isChain(trios):
+		return Hand{Kind: KindSeqTrio, Rank: trios[0], Length: len(trios)}, nil
+	case len(trios) >= 2 && len(trios)*4 == total && len(singles) == len(trios) && isChain(trios):
+		return Hand{Kind: KindSeqTrioSingle, Rank: trios[0], Length: len(trios), Kickers: singles}, nil
+	case len(trios) >= 2 && len(trios)*5 == total && len(pairs) == len(trios) && isChain(trios):
+		return Hand{Kind: KindSeqTrioPair, Rank: trios[0], Length: len(trios), Kickers: pairs}, nil
+	}
+	return Hand{}, ErrInvalidHand
+}
+
+// isChain 判断升序排列的点数是否首尾相连且最大不超过 A。
+func isChain(ranks []int) bool {
+	if len(ranks) == 0 || ranks[len(ranks)-1] > RankA {
+		return false
+	}
+	for i := 1; i < len(ranks); i++ {
+		if ranks[i] != ranks[i-1]+1 {
+			return false
+		}
+	}
+	return true
+}
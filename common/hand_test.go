@@ -0,0 +1,205 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// ruleCount 是 static/rule.json 中全部组合的个数。
+const ruleCount = 13998
+
+// generateSeq 生成从 seq 的每个位置开始、连续 num 个且最大到 A 的连牌。
+func generateSeq(num int, seq []string) (res []string) {
+	for i := range seq {
+		if i+num > 12 {
+			break
+		}
+		var sec string
+		for j := i; j < i+num; j++ {
+			sec += seq[j]
+		}
+		res = append(res, sec)
+	}
+	return
+}
+
+// combination 生成从 seq 中选出 num 个不同单牌的所有组合。
+func combination(seq []string, num int) (comb []string) {
+	if len(seq) < num {
+		return
+	}
+	if num == 1 {
+		return seq
+	}
+	if len(seq) == num {
+		allSingle := ""
+		for _, single := range seq {
+			allSingle += single
+		}
+		return []string{allSingle}
+	}
+	comb = append(comb, combination(seq[1:], num)...)
+	for _, c := range combination(seq[1:], num-1) {
+		comb = append(comb, seq[0]+c)
+	}
+	return
+}
+
+// without 返回去掉牌面字符在 chars 中的单牌之后的 singles。
+func without(singles []string, chars string) (res []string) {
+next:
+	for _, single := range singles {
+		for i := range chars {
+			if single[0] == chars[i] {
+				continue next
+			}
+		}
+		res = append(res, single)
+	}
+	return
+}
+
+// hasJoker 判断牌面字符串中是否有王。
+func hasJoker(cards string) bool {
+	for i := range cards {
+		if cards[i] == 'w' || cards[i] == 'W' {
+			return true
+		}
+	}
+	return false
+}
+
+// generateRules 按原先生成 static/rule.json 的方式生成牌型名称到牌面组合的映射。
+func generateRules() map[string][]string {
+	rules := map[string][]string{}
+	for _, c := range "34567890JQKA2" {
+		card := string(c)
+		rules["single"] = append(rules["single"], card)
+		rules["pair"] = append(rules["pair"], card+card)
+		rules["trio"] = append(rules["trio"], card+card+card)
+		rules["bomb"] = append(rules["bomb"], card+card+card+card)
+	}
+	for num := 5; num <= 12; num++ {
+		rules["seq_single"+strconv.Itoa(num)] = generateSeq(num, rules["single"])
+	}
+	for num := 3; num <= 10; num++ {
+		rules["seq_pair"+strconv.Itoa(num)] = generateSeq(num, rules["pair"])
+	}
+	for num := 2; num <= 6; num++ {
+		rules["seq_trio"+strconv.Itoa(num)] = generateSeq(num, rules["trio"])
+	}
+	rules["single"] = append(rules["single"], "w", "W")
+	rules["rocket"] = []string{"Ww"}
+
+	for _, t := range rules["trio"] {
+		for _, s := range rules["single"] {
+			if s[0] != t[0] {
+				rules["trio_single"] = append(rules["trio_single"], t+s)
+			}
+		}
+		for _, p := range rules["pair"] {
+			if p[0] != t[0] {
+				rules["trio_pair"] = append(rules["trio_pair"], t+p)
+			}
+		}
+	}
+	for num := 2; num <= 5; num++ {
+		for _, seqTrio := range rules["seq_trio"+strconv.Itoa(num)] {
+			for _, comb := range combination(without(rules["single"], seqTrio), num) {
+				name := "seq_trio_single" + strconv.Itoa(num)
+				rules[name] = append(rules[name], seqTrio+comb)
+				if !hasJoker(comb) {
+					name = "seq_trio_pair" + strconv.Itoa(num)
+					rules[name] = append(rules[name], seqTrio+comb+comb)
+				}
+			}
+		}
+	}
+	for _, b := range rules["bomb"] {
+		for _, comb := range combination(without(rules["single"], b[:1]), 2) {
+			rules["bomb_single"] = append(rules["bomb_single"], b+comb)
+			if !hasJoker(comb) {
+				rules["bomb_pair"] = append(rules["bomb_pair"], b+comb+comb)
+			}
+		}
+	}
+	return rules
+}
+
+// toCards 将牌面字符串转换为互不相同的牌编号。
+func toCards(t *testing.T, s string) []int {
+	used := make(map[byte]int)
+	cards := make([]int, 0, len(s))
+	for i := range s {
+		pokers := ToPoker(s[i])
+		if used[s[i]] >= len(pokers) {
+			t.Fatalf("%q: too many %q", s, s[i])
+		}
+		cards = append(cards, pokers[used[s[i]]])
+		used[s[i]]++
+	}
+	return cards
+}
+
+func TestClassifyMatchesRuleTable(t *testing.T) {
+	rules := generateRules()
+	total := 0
+	for name, combs := range rules {
+		for _, comb := range combs {
+			total++
+			hand, err := Classify(toCards(t, comb))
+			if err != nil {
+				t.Errorf("Classify(%q) error: %v, want %s", comb, err, name)
+				continue
+			}
+			if hand.Type() != name {
+				t.Errorf("Classify(%q) = %s, want %s", comb, hand.Type(), name)
+			}
+		}
+	}
+	if total != ruleCount {
+		t.Errorf("generated %d combinations, want %d", total, ruleCount)
+	}
+}
+
+// normalize 将每个组合中的牌排序，再将所有组合排序，rule.json 中组合的牌序与生成的不一定相同。
+func normalize(combs []string) []string {
+	res := make([]string, 0, len(combs))
+	for _, comb := range combs {
+		res = append(res, SortStr(comb))
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestGeneratedRulesMatchRuleJSON(t *testing.T) {
+	data, err := ioutil.ReadFile("../static/rule.json")
+	if err != nil {
+		t.Skip("static/rule.json not found")
+	}
+	var want map[string][]string
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	got := generateRules()
+	if len(got) != len(want) {
+		t.Errorf("generated %d kinds, rule.json has %d", len(got), len(want))
+	}
+	for name, combs := range want {
+		a := normalize(combs)
+		b := normalize(got[name])
+		if len(a) != len(b) {
+			t.Errorf("%s: generated %d combinations, rule.json has %d", name, len(b), len(a))
+			continue
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				t.Errorf("%s: generated %q, rule.json has %q", name, b[i], a[i])
+				break
+			}
+		}
+	}
+}
@@ -17,3 +17,27 @@ type Account struct {
 	CreatedDate string `json:"created_date" db:"created_date"`
 	UpdateDate  string `json:"updated_date" db:"updated_date"`
 }
+
+// PlayerStats 是玩家的对局统计，由 game_player 表中该玩家的记录汇总而来：
+// - UserId: 玩家 ID
+// - Games: 总局数，Wins: 获胜局数
+// - LandlordGames、LandlordWins、LandlordWinRate: 当地主的局数、获胜局数和胜率
+// - FarmerGames、FarmerWins、FarmerWinRate: 当农民的局数、获胜局数和胜率
+// - BiggestWin: 单局赢得最多的金币
+// - CurrentStreak: 当前连胜（正数）或连败（负数）的局数
+// - LongestWinStreak、LongestLoseStreak: 最长连胜和最长连败的局数
+type PlayerStats struct {
+	UserId            int     `json:"user_id"`
+	Games             int     `json:"games"`
+	Wins              int     `json:"wins"`
+	LandlordGames     int     `json:"landlord_games"`
+	LandlordWins      int     `json:"landlord_wins"`
+	LandlordWinRate   float64 `json:"landlord_win_rate"`
+	FarmerGames       int     `json:"farmer_games"`
+	FarmerWins        int     `json:"farmer_wins"`
+	FarmerWinRate     float64 `json:"farmer_win_rate"`
+	BiggestWin        int     `json:"biggest_win"`
+	CurrentStreak     int     `json:"current_streak"`
+	LongestWinStreak  int     `json:"longest_win_streak"`
+	LongestLoseStreak int     `json:"longest_lose_streak"`
+}
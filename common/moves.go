@@ -0,0 +1,259 @@
+package common
+
+import (
+	"sort"
+)
+
+// rankChars 是按点数从小到大排列的牌面字符，下标即 CardRank 返回的点数。
+const rankChars = "34567890JQKA2wW"
+
+// allKinds 是自由出牌时需要列举的全部牌型。
+var allKinds = []HandKind{
+	KindSingle, KindPair, KindTrio, KindTrioSingle, KindTrioPair,
+	KindSeqSingle, KindSeqPair, KindSeqTrio, KindSeqTrioSingle, KindSeqTrioPair,
+	KindBombSingle, KindBombPair, KindBomb, KindRocket,
+}
+
+// LegalMoves 按经典玩法列出手牌 hand 面对上家出牌 lastPlay 时所有合法的出法。
+// 详见 LegalMovesWith。
+func LegalMoves(hand, lastPlay []int) [][]int {
+	return LegalMovesWith(ClassicRules{}, hand, lastPlay)
+}
+
+// LegalMovesWith 按玩法 rules 列出手牌 hand 面对上家出牌 lastPlay 时所有合法的出法。
+//
+// lastPlay 为空表示自由出牌，此时列出手牌能组成的全部牌型，不能不出。
+// 否则列出所有能压过 lastPlay 的出法：同牌型更大的牌、所有炸弹和王炸，
+// 最后附上一个空切片表示不出。
+//
+// 点数相同的组合只列出一次，并通过 pokersInHand 对应到手牌中具体的牌编号。
+// 出法按从小到大排列：普通牌型在前，其次是炸弹，王炸最后；同一档按点数、张数升序。
+func LegalMovesWith(rules RuleSet, hand, lastPlay []int) (moves [][]int) {
+	var counts [rankCount]int
+	for _, card := range hand {
+		counts[CardRank(card)]++
+	}
+
+	kinds := allKinds
+	length := 0
+	var last Hand
+	following := len(lastPlay) > 0
+	if following {
+		var err error
+		last, err = rules.Classify(lastPlay)
+		if err != nil {
+			return [][]int{{}}
+		}
+		kinds = []HandKind{last.Kind, KindBomb, KindRocket}
+		if !last.IsBomb() {
+			length = last.Length
+		}
+	}
+
+	var found [][]int
+	seen := make(map[string]bool)
+	for _, kind := range kinds {
+		for _, ranks := range rankCandidates(counts, kind, length) {
+			key := ranksString(ranks)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			cards := pokersInHand(hand, key)
+			h, err := rules.Classify(cards)
+			if err != nil || len(cards) != len(ranks) {
+				continue
+			}
+			if following && rules.Compare(last, h) < 1 {
+				continue
+			}
+			found = append(found, cards)
+		}
+	}
+	sortMoves(rules, found)
+	moves = found
+	if following {
+		moves = append(moves, []int{})
+	}
+	return
+}
+
+// sortMoves 将若干手牌按玩法 rules 从小到大排序：普通牌型在前，其次是炸弹，王炸最后；
+// 炸弹先比张数，同一档按点数、张数升序。
+func sortMoves(rules RuleSet, moves [][]int) {
+	type move struct {
+		cards []int
+		hand  Hand
+	}
+	sorted := make([]move, 0, len(moves))
+	for _, cards := range moves {
+		hand, _ := rules.Classify(cards)
+		sorted = append(sorted, move{cards: cards, hand: hand})
+	}
+	tier := func(h Hand) int {
+		switch h.Kind {
+		case KindRocket:
+			return 2
+		case KindBomb:
+			return 1
+		}
+		return 0
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].hand, sorted[j].hand
+		if tier(a) != tier(b) {
+			return tier(a) < tier(b)
+		}
+		if tier(a) == 1 && a.Length != b.Length {
+			return a.Length < b.Length
+		}
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		return len(sorted[i].cards) < len(sorted[j].cards)
+	})
+	for i, m := range sorted {
+		moves[i] = m.cards
+	}
+}
+
+// ranksString 将点数列表转换为升序排列的牌面字符串，例如 [1 0 0] 转换为 "334"。
+func ranksString(ranks []int) string {
+	sorted := append([]int(nil), ranks...)
+	sort.Ints(sorted)
+	res := make([]byte, 0, len(sorted))
+	for _, rank := range sorted {
+		res = append(res, rankChars[rank])
+	}
+	return string(res)
+}
+
+// rankCandidates 列出手牌点数分布 counts 能组成的 kind 牌型的点数组合。
+// length 大于 0 时只列出该长度的连牌，为 0 时列出所有长度。
+// 返回的组合只保证手牌够用，是否真正合法由玩法的 Classify 判断。
+func rankCandidates(counts [rankCount]int, kind HandKind, length int) (res [][]int) {
+	// ranksWith 返回张数不少于 n 且不在 exclude 中的点数
+	ranksWith := func(n int, exclude ...int) (ranks []int) {
+	next:
+		for rank, count := range counts {
+			if count < n {
+				continue
+			}
+			for _, e := range exclude {
+				if rank == e {
+					continue next
+				}
+			}
+			ranks = append(ranks, rank)
+		}
+		return
+	}
+
+	switch kind {
+	case KindSingle, KindPair, KindTrio:
+		width := map[HandKind]int{KindSingle: 1, KindPair: 2, KindTrio: 3}[kind]
+		for _, rank := range ranksWith(width) {
+			res = append(res, repeatRank(rank, width))
+		}
+	case KindBomb:
+		for _, rank := range ranksWith(4, RankBlackJoker, RankRedJoker) {
+			for n := 4; n <= counts[rank]; n++ {
+				res = append(res, repeatRank(rank, n))
+			}
+		}
+	case KindRocket:
+		for n := 1; n <= counts[RankBlackJoker] && n <= counts[RankRedJoker]; n++ {
+			res = append(res, append(repeatRank(RankBlackJoker, n), repeatRank(RankRedJoker, n)...))
+		}
+	case KindTrioSingle, KindTrioPair:
+		width := 1
+		if kind == KindTrioPair {
+			width = 2
+		}
+		for _, trio := range ranksWith(3) {
+			for _, kicker := range ranksWith(width, trio) {
+				res = append(res, append(repeatRank(trio, 3), repeatRank(kicker, width)...))
+			}
+		}
+	case KindBombSingle, KindBombPair:
+		width := 1
+		if kind == KindBombPair {
+			width = 2
+		}
+		for _, bomb := range ranksWith(4, RankBlackJoker, RankRedJoker) {
+			for _, kickers := range chooseRanks(ranksWith(width, bomb), 2) {
+				ranks := repeatRank(bomb, 4)
+				for _, kicker := range kickers {
+					ranks = append(ranks, repeatRank(kicker, width)...)
+				}
+				res = append(res, ranks)
+			}
+		}
+	case KindSeqSingle:
+		res = rankChains(counts, 1, 5, length)
+	case KindSeqPair:
+		res = rankChains(counts, 2, 3, length)
+	case KindSeqTrio:
+		res = rankChains(counts, 3, 2, length)
+	case KindSeqTrioSingle, KindSeqTrioPair:
+		width := 1
+		if kind == KindSeqTrioPair {
+			width = 2
+		}
+		for _, chain := range rankChains(counts, 3, 2, length) {
+			trios := make([]int, 0, len(chain)/3)
+			for i := 0; i < len(chain); i += 3 {
+				trios = append(trios, chain[i])
+			}
+			for _, kickers := range chooseRanks(ranksWith(width, trios...), len(trios)) {
+				ranks := append([]int(nil), chain...)
+				for _, kicker := range kickers {
+					ranks = append(ranks, repeatRank(kicker, width)...)
+				}
+				res = append(res, ranks)
+			}
+		}
+	}
+	return
+}
+
+// rankChains 列出每个点数取 width 张、至少 minLen 组、最大到 A 的连牌。
+// length 大于 0 时只列出恰好 length 组的连牌。
+func rankChains(counts [rankCount]int, width, minLen, length int) (res [][]int) {
+	for start := 0; start <= RankA; start++ {
+		for end := start; end <= RankA && counts[end] >= width; end++ {
+			n := end - start + 1
+			if n < minLen || (length > 0 && n != length) {
+				continue
+			}
+			chain := make([]int, 0, n*width)
+			for rank := start; rank <= end; rank++ {
+				chain = append(chain, repeatRank(rank, width)...)
+			}
+			res = append(res, chain)
+		}
+	}
+	return
+}
+
+// chooseRanks 从 pool 中选出 k 个不同点数的所有组合。
+func chooseRanks(pool []int, k int) (res [][]int) {
+	if k == 0 {
+		return [][]int{{}}
+	}
+	for i := 0; i+k <= len(pool); i++ {
+		for _, rest := range chooseRanks(pool[i+1:], k-1) {
+			res = append(res, append([]int{pool[i]}, rest...))
+		}
+	}
+	return
+}
+
+// repeatRank 返回由 n 个 rank 组成的切片。
+func repeatRank(rank, n int) []int {
+	ranks := make([]int, n)
+	for i := range ranks {
+		ranks[i] = rank
+	}
+	return ranks
+}
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomHand 从点数在 [lo, lo+span) 之间的牌和大小王中随机抽出 n 张，点数集中时更容易组成连牌和炸弹。
+func randomHand(r *rand.Rand, rules RuleSet, lo, span, n int) []int {
+	var deck []int
+	for card := 0; card < rules.DeckSize(); card++ {
+		rank := CardRank(card)
+		if rank >= RankBlackJoker || rank >= lo && rank < lo+span {
+			deck = append(deck, card)
+		}
+	}
+	r.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+	if n > len(deck) {
+		n = len(deck)
+	}
+	return deck[:n]
+}
+
+// subsets 列出 hand 所有非空子集中牌型合法的组合，返回点数组合到牌型的映射。
+func subsets(rules RuleSet, hand []int) map[string]Hand {
+	res := make(map[string]Hand)
+	for mask := 1; mask < 1<<uint(len(hand)); mask++ {
+		var cards, ranks []int
+		for i, card := range hand {
+			if mask&(1<<uint(i)) != 0 {
+				cards = append(cards, card)
+				ranks = append(ranks, CardRank(card))
+			}
+		}
+		if h, err := rules.Classify(cards); err == nil {
+			res[ranksString(ranks)] = h
+		}
+	}
+	return res
+}
+
+// cardsString 将牌编号转换为按点数升序排列的牌面字符串。
+func cardsString(cards []int) string {
+	ranks := make([]int, 0, len(cards))
+	for _, card := range cards {
+		ranks = append(ranks, CardRank(card))
+	}
+	return ranksString(ranks)
+}
+
+// TestLegalMovesBruteForce 对随机的手牌，将 LegalMovesWith 的结果与穷举所有子集、
+// 逐一用 Classify 和 Compare 判断得到的出法比较，两者按点数组合应完全一致。
+func TestLegalMovesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, rules := range []RuleSet{ClassicRules{}, NoKickerBombRules{}, TwoDeckRules{}} {
+		for round := 0; round < 200; round++ {
+			lo := r.Intn(RankBlackJoker)
+			span := 3 + r.Intn(8)
+			hand := randomHand(r, rules, lo, span, 6+r.Intn(7))
+
+			var lastPlay []int
+			var last Hand
+			if round%2 == 1 {
+				other := randomHand(r, rules, lo, span, 8)
+				var plays [][]int
+				for mask := 1; mask < 1<<uint(len(other)); mask++ {
+					var cards []int
+					for i, card := range other {
+						if mask&(1<<uint(i)) != 0 {
+							cards = append(cards, card)
+						}
+					}
+					if _, err := rules.Classify(cards); err == nil {
+						plays = append(plays, cards)
+					}
+				}
+				lastPlay = plays[r.Intn(len(plays))]
+				last, _ = rules.Classify(lastPlay)
+			}
+
+			want := make(map[string]bool)
+			for key, h := range subsets(rules, hand) {
+				if lastPlay == nil || rules.Compare(last, h) > 0 {
+					want[key] = true
+				}
+			}
+
+			moves := LegalMovesWith(rules, hand, lastPlay)
+			if lastPlay != nil {
+				if n := len(moves); n == 0 || len(moves[n-1]) != 0 {
+					t.Fatalf("%s: hand %s last %s: moves do not end with pass", rules.Name(), cardsString(hand), cardsString(lastPlay))
+				}
+				moves = moves[:len(moves)-1]
+			}
+			got := make(map[string]bool)
+			inHand := make(map[int]bool)
+			for _, card := range hand {
+				inHand[card] = true
+			}
+			for _, move := range moves {
+				used := make(map[int]bool)
+				for _, card := range move {
+					if !inHand[card] || used[card] {
+						t.Fatalf("%s: hand %s: move %v uses cards not in hand", rules.Name(), cardsString(hand), move)
+					}
+					used[card] = true
+				}
+				key := cardsString(move)
+				if got[key] {
+					t.Errorf("%s: hand %s: move %s listed twice", rules.Name(), cardsString(hand), key)
+				}
+				got[key] = true
+			}
+
+			for key := range want {
+				if !got[key] {
+					t.Errorf("%s: hand %s last %s: missing %s", rules.Name(), cardsString(hand), cardsString(lastPlay), key)
+				}
+			}
+			for key := range got {
+				if !want[key] {
+					t.Errorf("%s: hand %s last %s: unexpected %s", rules.Name(), cardsString(hand), cardsString(lastPlay), key)
+				}
+			}
+		}
+	}
+}
@@ -33,6 +33,8 @@ const (
 	ReqTableList = 15
 
 	// ResTableList represents the response constant for sending existing table information within a room.
+	// Each table is [table ID, seated players, in game (1 or 0)], the same as in ResJoinRoom;
+	// full tables are listed too so that they can be watched with ReqWatchTable.
 	ResTableList = 16
 
 	// ReqJoinRoom is a constant representing the request to join a room.
@@ -43,6 +45,7 @@ const (
 	ReqJoinTable = 19
 
 	// ResJoinTable represents the constant value for the response of joining a table.
+	// 携带牌桌 ID、按座位号排列的玩家（空座位的玩家 ID 为 -1）和观战者，玩家进出座位或者观战者进出时都会重新发送。
 	ResJoinTable = 20
 
 	// ReqNewTable represents the constant value for the request to create a new table.
@@ -56,6 +59,7 @@ const (
 	ReqDealPoker = 31
 
 	// ResDealPoker represents the response code for dealing poker in the poker game.
+	// 携带第一个叫分的玩家 ID、玩家的手牌和牌桌的叫地主方式（0 为叫分，1 为抢地主）。
 	ResDealPoker = 32
 
 	// ReqCallScore represents the constant value for the request to call a score in a game.
@@ -119,4 +123,101 @@ const (
 
 	// ResRestart 表示重新开始游戏的响应常量。
 	ResRestart = 46
+
+	// ReqHint 表示请求出牌提示。
+	// 轮到玩家出牌时，每次请求按从小到大的顺序返回下一种能出的牌，到最大后从头循环。
+	ReqHint = 47
+
+	// ResHint 是出牌提示的响应代码，携带建议出的牌的编号，没有能压过上家的牌时为空数组。
+	ResHint = 48
+
+	// ReqReplay 表示请求播放录像，参数为录像 ID、播放速度（可选，默认 1 倍速）和观看视角的玩家 ID（可选）。
+	// 服务器先回复 ResReplay，然后按录制时的节奏把这局牌的消息依次推送给客户端。
+	ReqReplay = 49
+
+	// ResReplay 是播放录像的响应代码，携带录像 ID、观看视角的玩家 ID，录像不存在时玩家 ID 为 0。
+	ResReplay = 50
+
+	// ReqReplaySpeed 表示调整正在播放的录像的速度，参数为播放速度。
+	ReqReplaySpeed = 51
+
+	// ResJoinRoomFail 表示拒绝玩家进入房间或在房间中入座，携带房间 ID、玩家的金币余额，
+	// 以及房间允许的最少和最多金币（为 0 时不限制）。
+	ResJoinRoomFail = 52
+
+	// ReqPass 表示玩家不出，自由出牌（本局第一手或者其他玩家都不出）时不能不出。
+	ReqPass = 53
+
+	// ResPass 是不出的广播，携带不出的玩家 ID，以及这一轮是否结束。
+	// 一轮结束时上一次出的牌被清空，下一个玩家（最后出牌的玩家）自由出牌。
+	ResPass = 54
+
+	// ResError 表示服务器拒绝了客户端的请求，只发给发出请求的客户端。
+	// 携带错误代码（ErrCode 开头的常量）、被拒绝的请求代码和可以展示给玩家的原因。
+	ResError = 55
+
+	// ResTurn 表示轮到某个玩家叫分或出牌并开始计时，携带玩家 ID、时限（秒）和截止时间（Unix 毫秒）。
+	// 超时后服务器代为不叫、不出，或者在需要自由出牌时出最小的一张牌。
+	ResTurn = 56
+
+	// ResAutoPlay 表示玩家进入或退出托管，携带玩家 ID 和是否托管，托管时由机器人代为叫分和出牌。
+	ResAutoPlay = 57
+
+	// ReqAutoPlay 表示玩家开启或取消托管，参数为是否托管。
+	// 轮到托管的玩家时由机器人代为叫分和出牌，断线的玩家也会被托管到本局结束。
+	ReqAutoPlay = 58
+
+	// ResSnapshot 是断线重连后牌局的全貌，包括手牌、玩家及其角色、叫分、底牌、上一手牌、轮到的玩家、倍数和每个玩家剩余的张数。
+	// 玩家在牌局进行中重新连接时，服务器将新的连接接到原来的座位上，取消托管，并首先发送 ResSnapshot。
+	ResSnapshot = 59
+
+	// ReqWatchTable 表示以观战者的身份进入房间中的牌桌，参数为牌桌 ID。观战者不占座位，不能叫分和出牌。
+	ReqWatchTable = 60
+
+	// ResWatchTable 表示开始观战，携带牌桌 ID 和观战者视角的牌局快照（不含任何玩家的手牌）。
+	// 之后观战者会收到牌桌上公开的消息，发牌时玩家的手牌以 HiddenPoker 代替。
+	ResWatchTable = 61
+
+	// ResRedeal 表示所有人都不叫，重新发牌，携带连续重新发牌的次数和新的第一个叫分的玩家 ID，随后发送 ResDealPoker。
+	// 连续重新发牌的次数达到房间的上限后不再重新发牌，而是强制第一个叫分的玩家当地主。
+	ResRedeal = 62
+
+	// ReqRob 表示抢地主玩法中叫地主或抢地主，参数为 true 表示叫或抢，false 表示不叫或不抢。
+	// 叫地主的底分为 1 分，每抢一次倍数翻倍；叫分玩法的牌桌不接受 ReqRob，抢地主玩法的牌桌不接受 ReqCallScore。
+	ReqRob = 63
+
+	// ResRob 表示玩家叫地主或抢地主，携带玩家 ID、是否叫或抢、当前的倍数和下一个轮到的玩家 ID，
+	// 叫地主结束时下一个玩家 ID 为 0，随后发送 ResShowPoker 或者 ResRedeal。
+	ResRob = 64
+)
+
+// HiddenPoker 代替发给观战者的消息中不公开的牌。
+const HiddenPoker = -1
+
+// ResError 中的错误代码。
+const (
+
+	// ErrCodeBadRequest 表示请求缺少参数或参数格式错误。
+	ErrCodeBadRequest = 1
+
+	// ErrCodeWrongState 表示当前不能进行该操作，例如还没有加入牌桌，或者牌桌不在叫分、出牌阶段。
+	ErrCodeWrongState = 2
+
+	// ErrCodeNotYourTurn 表示还没有轮到该玩家叫分或出牌。
+	ErrCodeNotYourTurn = 3
+
+	// ErrCodeInvalidScore 表示叫分超出范围或者没有高于当前的最高叫分。
+	ErrCodeInvalidScore = 4
+
+	// ErrCodeNotInHand 表示出的牌不在玩家手中，或者同一张牌出了多次。
+	ErrCodeNotInHand = 5
+
+	// ErrCodeInvalidHand 表示出的牌不是合法的牌型，或者房间的玩法不允许这种牌型。
+	ErrCodeInvalidHand = 6
+
+	// ErrCodeNotBigger 表示出的牌没有压过上家。
+	ErrCodeNotBigger = 7
+
+	// ErrCodeCannotPass 表示自由出牌时不能不出。
+	ErrCodeCannotPass = 8
 )
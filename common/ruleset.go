@@ -0,0 +1,176 @@
+package common
+
+import "errors"
+
+var (
+	// ErrForbiddenHand 表示牌型合法，但当前玩法不允许出这种牌型。
+	ErrForbiddenHand = errors.New("hand kind not allowed by rule set")
+
+	// ErrNotBigger 表示出的牌压不过上家的牌。
+	ErrNotBigger = errors.New("hand does not beat last play")
+)
+
+// RuleSet 定义一种斗地主玩法：发牌张数、允许的牌型、牌型的大小比较以及加倍的条件。
+// 房间通过 RuleSet 选择玩法，牌桌发牌和校验出牌都经由它完成。
+type RuleSet interface {
+	// Name 返回玩法的名称。
+	Name() string
+
+	// Players 返回每张牌桌的玩家人数。
+	Players() int
+
+	// DeckSize 返回整副牌的张数，牌的编号为 0 ~ DeckSize()-1。
+	DeckSize() int
+
+	// HandSize 返回每个玩家发到的张数，剩下的牌作为底牌。
+	HandSize() int
+
+	// Classify 分析一手牌的牌型，本玩法不允许的牌型返回 ErrForbiddenHand。
+	Classify(cards []int) (Hand, error)
+
+	// Compare 比较两手牌，返回值大于 0 表示 compared 能压过 base。
+	Compare(base, compared Hand) int
+
+	// Multiple 返回打出这手牌后倍数需要乘上的因子，不加倍时为 1。
+	Multiple(hand Hand) int
+}
+
+// RuleSets 是所有可供房间选择的玩法，以玩法名称为键。
+var RuleSets = map[string]RuleSet{
+	ClassicRules{}.Name():      ClassicRules{},
+	NoKickerBombRules{}.Name(): NoKickerBombRules{},
+	TwoDeckRules{}.Name():      TwoDeckRules{},
+}
+
+// ValidatePlay 使用玩法 rules 校验出牌 shot 是否合法并且能压过上家的出牌 lastShot。
+// lastShot 为空表示自由出牌，只校验牌型。
+// 校验通过时返回 shot 的牌型。
+func ValidatePlay(rules RuleSet, lastShot, shot []int) (hand Hand, err error) {
+	hand, err = rules.Classify(shot)
+	if err != nil || len(lastShot) == 0 {
+		return
+	}
+	last, err := rules.Classify(lastShot)
+	if err != nil {
+		return hand, nil
+	}
+	if rules.Compare(last, hand) < 1 {
+		err = ErrNotBigger
+	}
+	return
+}
+
+// ClassicRules 是经典的三人一副牌玩法，每人 17 张，留 3 张底牌，允许四带二。
+type ClassicRules struct{}
+
+func (ClassicRules) Name() string { return "classic" }
+
+func (ClassicRules) Players() int { return 3 }
+
+func (ClassicRules) DeckSize() int { return 54 }
+
+func (ClassicRules) HandSize() int { return 17 }
+
+func (ClassicRules) Classify(cards []int) (Hand, error) {
+	return Classify(cards)
+}
+
+// Compare 同牌型按点数比较，王炸最大，炸弹可以压过除王炸以外的其他牌型，其余情况无法比较时返回 0。
+func (ClassicRules) Compare(base, compared Hand) int {
+	switch {
+	case base.Type() == compared.Type():
+		return compared.Rank - base.Rank
+	case compared.Kind == KindRocket:
+		return 1
+	case base.Kind == KindRocket:
+		return -1
+	case compared.Kind == KindBomb:
+		return 1
+	}
+	return 0
+}
+
+// Multiple 出炸弹或王炸时倍数翻倍。
+func (ClassicRules) Multiple(hand Hand) int {
+	if hand.IsBomb() {
+		return 2
+	}
+	return 1
+}
+
+// NoKickerBombRules 与经典玩法相同，但不允许四带二（bomb_single、bomb_pair）。
+type NoKickerBombRules struct {
+	ClassicRules
+}
+
+func (NoKickerBombRules) Name() string { return "no_kicker_bomb" }
+
+func (NoKickerBombRules) Classify(cards []int) (Hand, error) {
+	hand, err := Classify(cards)
+	if err == nil && (hand.Kind == KindBombSingle || hand.Kind == KindBombPair) {
+		return Hand{}, ErrForbiddenHand
+	}
+	return hand, err
+}
+
+// TwoDeckRules 是四人两副牌玩法，每人 25 张，留 8 张底牌。
+// 第二副牌的编号为 54~107。同一点数 4 到 8 张都是炸弹，张数多的炸弹更大，
+// 四张王组成王炸，两张王不再是王炸；炸弹不能带牌。
+type TwoDeckRules struct {
+	ClassicRules
+}
+
+func (TwoDeckRules) Name() string { return "two_deck" }
+
+func (TwoDeckRules) Players() int { return 4 }
+
+func (TwoDeckRules) DeckSize() int { return 108 }
+
+func (TwoDeckRules) HandSize() int { return 25 }
+
+func (r TwoDeckRules) Classify(cards []int) (Hand, error) {
+	counts, err := countRanks(cards, r.DeckSize())
+	if err != nil {
+		return Hand{}, err
+	}
+	total := len(cards)
+	if total == 4 && counts[RankBlackJoker] == 2 && counts[RankRedJoker] == 2 {
+		return Hand{Kind: KindRocket, Rank: RankRedJoker, Length: total}, nil
+	}
+	if rank := CardRank(cards[0]); total >= 4 && counts[rank] == total && rank < RankBlackJoker {
+		return Hand{Kind: KindBomb, Rank: rank, Length: total}, nil
+	}
+	hand, err := classifyCounts(counts, total)
+	if err != nil {
+		return hand, err
+	}
+	switch hand.Kind {
+	case KindRocket:
+		return Hand{}, ErrInvalidHand
+	case KindBombSingle, KindBombPair:
+		return Hand{}, ErrForbiddenHand
+	}
+	return hand, nil
+}
+
+// Compare 王炸最大；炸弹之间先比张数再比点数；炸弹可以压过其他牌型；其余同牌型按点数比较。
+func (TwoDeckRules) Compare(base, compared Hand) int {
+	switch {
+	case compared.Kind == KindRocket:
+		return 1
+	case base.Kind == KindRocket:
+		return -1
+	case compared.Kind == KindBomb && base.Kind == KindBomb:
+		if compared.Length != base.Length {
+			return compared.Length - base.Length
+		}
+		return compared.Rank - base.Rank
+	case compared.Kind == KindBomb:
+		return 1
+	case base.Kind == KindBomb:
+		return -1
+	case base.Type() == compared.Type():
+		return compared.Rank - base.Rank
+	}
+	return 0
+}
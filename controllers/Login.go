@@ -6,6 +6,7 @@ import (
 	"github.com/astaxie/beego/logs"
 	"landlord/common"
 	"net/http"
+	"strconv"
 )
 
 func Login(w http.ResponseWriter, r *http.Request) {
@@ -45,7 +46,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	if row != nil {
 		err := row.Scan(&account.Id, &account.Email, &account.Username, &account.Password, &account.Coin, &account.CreatedDate, &account.UpdateDate)
 		if err != nil {
-			cookie := http.Cookie{Name: "userid", Value: string(account.Id), Path: "/", MaxAge: 86400}
+			cookie := http.Cookie{Name: "userid", Value: strconv.Itoa(account.Id), Path: "/", MaxAge: 86400}
 			http.SetCookie(w, &cookie)
 			cookie = http.Cookie{Name: "username", Value: account.Username, Path: "/", MaxAge: 86400}
 			http.SetCookie(w, &cookie)
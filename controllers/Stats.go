@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PlayerStats 处理 /api/players/{id}/stats 请求，以 JSON 格式返回玩家的对局统计 common.PlayerStats。
+// 只统计真人玩家的对局记录，没有对局记录的玩家返回全为 0 的统计。
+func PlayerStats(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			logs.Error("user request PlayerStats - panic:%v ", r)
+		}
+	}()
+	path := strings.TrimPrefix(r.URL.Path, "/api/players/")
+	if !strings.HasSuffix(path, "/stats") {
+		http.NotFound(w, r)
+		return
+	}
+	userId, err := strconv.Atoi(strings.TrimSuffix(path, "/stats"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if common.GameConfInfo.Db == nil {
+		logs.Error("user request PlayerStats - database is not connected")
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	stats, err := playerStats(userId)
+	if err != nil {
+		logs.Error("user request PlayerStats - query player [%d] stats err: %v", userId, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if err = json.NewEncoder(w).Encode(stats); err != nil {
+		logs.Error("user request PlayerStats - write stats err: %v", err)
+	}
+}
+
+// playerStats 按对局顺序汇总玩家 userId 在 game_player 表中的记录。
+func playerStats(userId int) (stats common.PlayerStats, err error) {
+	stats.UserId = userId
+	rows, err := common.GameConfInfo.Db.Query("SELECT role, win, coin FROM `game_player` WHERE user_id=? AND robot=0 ORDER BY game_id", userId)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var role, coin int
+		var win bool
+		if err = rows.Scan(&role, &win, &coin); err != nil {
+			return
+		}
+		stats.Games++
+		if role == 1 { // 地主
+			stats.LandlordGames++
+		} else {
+			stats.FarmerGames++
+		}
+		if coin > stats.BiggestWin {
+			stats.BiggestWin = coin
+		}
+		if win {
+			stats.Wins++
+			if role == 1 {
+				stats.LandlordWins++
+			} else {
+				stats.FarmerWins++
+			}
+			if stats.CurrentStreak < 0 {
+				stats.CurrentStreak = 0
+			}
+			stats.CurrentStreak++
+			if stats.CurrentStreak > stats.LongestWinStreak {
+				stats.LongestWinStreak = stats.CurrentStreak
+			}
+		} else {
+			if stats.CurrentStreak > 0 {
+				stats.CurrentStreak = 0
+			}
+			stats.CurrentStreak--
+			if -stats.CurrentStreak > stats.LongestLoseStreak {
+				stats.LongestLoseStreak = -stats.CurrentStreak
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+	if stats.LandlordGames > 0 {
+		stats.LandlordWinRate = float64(stats.LandlordWins) / float64(stats.LandlordGames)
+	}
+	if stats.FarmerGames > 0 {
+		stats.FarmerWinRate = float64(stats.FarmerWins) / float64(stats.FarmerGames)
+	}
+	return
+}
@@ -45,16 +45,28 @@ func initSqlite() (err error) {
 		logs.Error("initSqlite err : %v", err)
 		return
 	}
-	var stmt *sql.Stmt
-	stmt, err = gameConf.Db.Prepare(`CREATE TABLE IF NOT EXISTS "account" ("id" INTEGER NOT NULL,"email" text(32),"username" TEXT(16),"password" TEXT(32),"coin" integer,"created_date" TEXT(32),"updated_date" TEXT(32),PRIMARY KEY ("id"))`)
-	if err != nil {
-		logs.Error("initSqlite err : %v", err)
-		return
+	schemas := []string{
+		`CREATE TABLE IF NOT EXISTS "account" ("id" INTEGER NOT NULL,"email" text(32),"username" TEXT(16),"password" TEXT(32),"coin" integer,"created_date" TEXT(32),"updated_date" TEXT(32),PRIMARY KEY ("id"))`,
+		`CREATE TABLE IF NOT EXISTS "replay" ("id" INTEGER NOT NULL,"table_id" integer,"rules" TEXT(32),"seed" integer,"players" TEXT,"created_date" TEXT(32),PRIMARY KEY ("id"))`,
+		`CREATE TABLE IF NOT EXISTS "replay_event" ("replay_id" integer NOT NULL,"seq" integer NOT NULL,"at" integer,"user_id" integer,"msg" TEXT,PRIMARY KEY ("replay_id","seq"))`,
+		`CREATE TABLE IF NOT EXISTS "game" ("id" INTEGER NOT NULL,"replay_id" integer,"table_id" integer,"rules" TEXT(32),"call_score" integer,"multiple" integer,"bombs" integer,"winner_role" integer,"duration" integer,"created_date" TEXT(32),PRIMARY KEY ("id"))`,
+		`CREATE TABLE IF NOT EXISTS "game_player" ("game_id" integer NOT NULL,"user_id" integer NOT NULL,"robot" integer,"role" integer,"call_score" integer,"bombs" integer,"win" integer,"coin" integer,PRIMARY KEY ("game_id","user_id"))`,
+		`CREATE INDEX IF NOT EXISTS "game_player_user_id" ON "game_player" ("user_id","game_id")`,
+		`CREATE TABLE IF NOT EXISTS "coin_ledger" ("id" INTEGER NOT NULL,"user_id" integer NOT NULL,"game_id" integer,"reason" TEXT(32),"amount" integer,"balance" integer,"created_date" TEXT(32),PRIMARY KEY ("id"))`,
+		`CREATE INDEX IF NOT EXISTS "coin_ledger_user_id" ON "coin_ledger" ("user_id")`,
 	}
-	_, err = stmt.Exec()
-	if err != nil {
-		logs.Error("create table err:", err)
-		return
+	for _, schema := range schemas {
+		var stmt *sql.Stmt
+		stmt, err = gameConf.Db.Prepare(schema)
+		if err != nil {
+			logs.Error("initSqlite err : %v", err)
+			return
+		}
+		_, err = stmt.Exec()
+		if err != nil {
+			logs.Error("create table err:", err)
+			return
+		}
 	}
 	return
 }
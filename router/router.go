@@ -11,8 +11,10 @@ func init() {
 	http.HandleFunc("/login", controllers.Login)
 	http.HandleFunc("/loginOut", controllers.LoginOut)
 	http.HandleFunc("/reg", controllers.Register)
+	http.HandleFunc("/api/players/", controllers.PlayerStats)
 
 	http.HandleFunc("/ws", service.ServeWs)
+	http.HandleFunc("/replay/", service.ServeReplay)
 
 	// 设置静态目录
 	static := http.FileServer(http.Dir("./static"))
@@ -8,6 +8,7 @@ import (
 	"landlord/common"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -70,37 +71,85 @@ type Client struct {
 	Table      *Table
 	HandPokers []int
 	Ready      bool
-	IsCalled   bool    //是否叫完分
-	Next       *Client //链表
+	IsCalled   bool //是否叫完分
+	Seat       int  //座位号
 	IsRobot    bool
 	toRobot    chan []interface{} //发送给robot的消息
 	toServer   chan []interface{} //robot发送给服务器
+	strategy   RobotStrategy      //robot的决策逻辑
+	hints      [][]int            //本轮出牌提示，出牌后清空
+	hintIndex  int                //下一次提示的下标
+	playback   *replayPlayback    //正在播放的录像
+	timeouts   int                //连续超时的次数
+	autoPlay   bool               //是否托管
+	offline    bool               //是否已断线，断线后座位由机器人托管到本局结束
+	watching   *Table             //正在观战的牌桌
+	writeLock  sync.Mutex         //串行化对连接的写入，gorilla/websocket 不允许并发写
 }
 
 // 重置客户端的状态。
 func (c *Client) reset() {
-	c.UserInfo.Role = 1
+	c.UserInfo.Role = RoleFarmer
 	c.HandPokers = make([]int, 0, 21)
 	c.Ready = false
 	c.IsCalled = false
+	c.hints = nil
 }
 
-// sendRoomTables 发送房间中存在的牌桌信息。
-func (c *Client) sendRoomTables() {
-	res := make([][2]int, 0)              // 一个空切片，用于存储桌子信息
-	for _, table := range c.Room.Tables { // 遍历房间中的每张桌子
-		if len(table.TableClients) < 3 { // 如果桌子的客户端少于 3 个
-			res = append(res, [2]int{int(table.TableId), len(table.TableClients)}) // 将桌子的id以及桌子中的客户端数量添加到结果切片中
+// nextHint 返回下一条出牌提示。
+// 第一次请求时按上家出牌列出所有能出的牌（不含不出），之后每次请求依次返回下一种，循环往复。
+// 没有能压过上家的牌时返回空切片。
+func (c *Client) nextHint() []int {
+	if c.hints == nil {
+		lastShotPoker := c.Table.GameManage.LastShotPoker
+		if c.Table.isLead(c) {
+			lastShotPoker = nil
+		}
+		c.hints = make([][]int, 0)
+		for _, move := range common.LegalMovesWith(c.Table.Rules, c.HandPokers, lastShotPoker) {
+			if len(move) > 0 {
+				c.hints = append(c.hints, move)
+			}
 		}
+		c.hintIndex = 0
+	}
+	if len(c.hints) == 0 {
+		return []int{}
+	}
+	hint := c.hints[c.hintIndex%len(c.hints)]
+	c.hintIndex++
+	return hint
+}
+
+// allowRoom 检查玩家的金币余额是否允许进入房间 room，不允许时回复 ResJoinRoomFail。
+// 机器人的金币不受限制。
+func (c *Client) allowRoom(room *Room) bool {
+	if c.IsRobot || room.allowCoin(c.UserInfo.Coin) {
+		return true
 	}
-	c.sendMsg([]interface{}{common.ResTableList, res}) // 调用 sendMsg() 方法，传递一个包含 'ResTableList' 常量和结果切片的切片
+	logs.Debug("user [%d] coin [%d] not allowed in room [%d]", c.UserInfo.UserId, c.UserInfo.Coin, room.RoomId)
+	c.sendMsg([]interface{}{common.ResJoinRoomFail, room.RoomId, c.UserInfo.Coin, room.minCoin(), room.MaxCoin})
+	return false
+}
+
+// sendError 以 ResError 告诉客户端请求 req 被拒绝及其原因。
+func (c *Client) sendError(req int, err *requestError) {
+	logs.Debug("user [%d] request [%d] rejected: %s", c.UserInfo.UserId, req, err.Reason)
+	c.sendMsg([]interface{}{common.ResError, err.Code, req, err.Reason})
+}
+
+// sendRoomTables 发送房间中存在的牌桌信息，见 Room.tables。
+func (c *Client) sendRoomTables() {
+	c.sendMsg([]interface{}{common.ResTableList, c.Room.tables()})
 }
 
 // sendMsg 将消息发送给客户端。
 //
 // 参数 msg 是要发送的消息，类型为 []interface{}。
 //
-// 如果客户端是机器人，则将消息发送到 toRobot 通道并返回。
+// 如果客户端是机器人，则将消息发送到 toRobot 通道并返回；如果客户端已断线，则丢弃消息。
+//
+// 计时器、录像播放和请求处理会在不同的 goroutine 中发送消息，写入连接时持有 writeLock。
 //
 // 否则，将消息序列化为 JSON 字节流，并写入到 WebSocket 连接中。
 // 在写入操作之前，会设置写入超时时间为 writeWait。
@@ -113,11 +162,16 @@ func (c *Client) sendMsg(msg []interface{}) {
 		c.toRobot <- msg
 		return
 	}
+	if c.offline {
+		return
+	}
 	msgByte, err := json.Marshal(msg)
 	if err != nil {
 		logs.Error("send msg [%v] marsha1 err:%v", string(msgByte), err)
 		return
 	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
 	err = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 	if err != nil {
 		logs.Error("send msg SetWriteDeadline [%v] err:%v", string(msgByte), err)
@@ -129,6 +183,7 @@ func (c *Client) sendMsg(msg []interface{}) {
 		if err != nil {
 			logs.Error("close client err: %v", err)
 		}
+		return
 	}
 	_, err = w.Write(msgByte)
 	if err != nil {
@@ -143,33 +198,27 @@ func (c *Client) sendMsg(msg []interface{}) {
 }
 
 // 关闭光比客户端连接
+// 玩家离开座位，是牌桌上最后一个玩家时删除牌桌；创建者离开时由座位号最小的玩家接替，
+// 下一局第一个叫分的玩家离开时由顺时针方向的下一个玩家接替，然后向牌桌同步座位。调用方需持有牌桌的锁。
 func (c *Client) close() {
 	if c.Table != nil {
-		for _, client := range c.Table.TableClients {
-			if c.Table.Creator == c && c != client {
-				c.Table.Creator = client
-			}
-			if c == client.Next {
-				client.Next = nil
-			}
-		}
-		if len(c.Table.TableClients) != 1 {
-			for _, client := range c.Table.TableClients {
-				if client != client.Table.Creator {
-					client.Table.Creator.Next = client
-				}
-			}
-		}
 		if len(c.Table.TableClients) == 1 {
+			c.Table.stopTurn()
+			for _, watcher := range c.Table.Watchers {
+				watcher.watching = nil
+			}
 			c.Table.Creator = nil
 			delete(c.Room.Tables, c.Table.TableId)
 			return
 		}
-		delete(c.Table.TableClients, c.UserInfo.UserId)
-		if c.Table.State == GamePlaying {
-			c.Table.syncUser()
-			//c.Table.reset()
+		if c.Table.GameManage.FirstCallScore == c {
+			c.Table.GameManage.FirstCallScore = c.Table.next(c)
+		}
+		c.Table.unsit(c)
+		if c.Table.Creator == c {
+			c.Table.Creator = c.Table.players(nil)[0]
 		}
+		c.Table.syncUser()
 		if c.IsRobot {
 			close(c.toRobot)
 			close(c.toServer)
@@ -177,26 +226,98 @@ func (c *Client) close() {
 	}
 }
 
+// keepSeat 在玩家的连接 conn 断开时保留其在进行中的牌局里的座位，交给机器人托管到本局结束或者玩家重连，返回是否保留了座位。
+// 不在牌桌上或者牌局没有在叫分、出牌阶段时不保留；玩家已经通过新的连接重连时直接返回 true。调用方需持有牌桌的锁。
+func (c *Client) keepSeat(conn *websocket.Conn) bool {
+	table := c.Table
+	if table == nil {
+		return false
+	}
+	if c.conn != conn {
+		return true
+	}
+	if !table.State.inGame() {
+		return false
+	}
+	logs.Debug("user [%d] offline, keep seat in table [%d]", c.UserInfo.UserId, table.TableId)
+	c.offline = true
+	table.setAutoPlay(c, true)
+	if table.turnClient() == c {
+		table.startTurn()
+	}
+	return true
+}
+
+// inGame 判断玩家是否在进行中（叫分或出牌阶段）的牌局里。
+func (c *Client) inGame() bool {
+	if c.Table == nil {
+		return false
+	}
+	c.Table.Lock.RLock()
+	defer c.Table.Lock.RUnlock()
+	return c.Table.State.inGame()
+}
+
+// leave 玩家离开牌桌，牌桌上没有真人玩家时机器人也随之离开。调用方需持有牌桌的锁。
+func (c *Client) leave() {
+	table := c.Table
+	c.close()
+	if table == nil {
+		return
+	}
+	for _, client := range table.TableClients {
+		if !client.IsRobot {
+			return
+		}
+	}
+	for _, client := range table.TableClients {
+		client.close()
+	}
+}
+
+// leaveTable 让玩家离开所在的牌桌，用于进入其他房间或牌桌之前，不在牌桌上时什么也不做。
+// 牌局在叫分或出牌阶段时不离开，返回 false。
+func (c *Client) leaveTable() bool {
+	table := c.Table
+	if table == nil {
+		return true
+	}
+	table.Lock.Lock()
+	defer table.Lock.Unlock()
+	if table.State.inGame() {
+		return false
+	}
+	if c.Table == table {
+		c.leave()
+		// 其他玩家可能都已经请求重新开始，只在等这个玩家
+		table.restart()
+	}
+	c.Table = nil
+	return true
+}
+
 // 处理读取客户端消息的循环，如果发生错误并且错误不是预期的关闭错误，则记录错误并退出循环。
 // 将消息进行修整，并尝试将其解析为JSON格式，然后将其交给wsRequest函数处理。
 func (c *Client) readPump() {
+	conn := c.conn
 	defer func() {
 		//logs.Debug("readPump exit")
-		c.conn.Close()
-		c.close()
-		if c.Room.AllowRobot {
-			if c.Table != nil {
-				for _, client := range c.Table.TableClients {
-					client.close()
-				}
+		c.stopReplay()
+		c.stopWatching()
+		conn.Close()
+		if table := c.Table; table != nil {
+			table.Lock.Lock()
+			if c.Table == table && !c.keepSeat(conn) {
+				c.leave()
 			}
+			table.Lock.Unlock()
 		}
 	}()
-	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error { conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
 	for {
-		_, message, err := c.conn.ReadMessage()
+		_, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				logs.Error("websocket user_id[%d] unexpected close error: %v", c.UserInfo.UserId, err)
@@ -216,6 +337,7 @@ func (c *Client) readPump() {
 
 // Ping 心跳，定期向客户端发送 ping 消息
 func (c *Client) Ping() {
+	conn := c.conn
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
@@ -223,14 +345,27 @@ func (c *Client) Ping() {
 	for {
 		select {
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			c.writeLock.Lock()
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := conn.WriteMessage(websocket.PingMessage, nil)
+			c.writeLock.Unlock()
+			if err != nil {
 				return
 			}
 		}
 	}
 }
 
+// loadCoin 从 account 表读取玩家的金币余额，没有配置数据库时返回 0。
+func loadCoin(userId UserId) (coin int, err error) {
+	db := common.GameConfInfo.Db
+	if db == nil {
+		return
+	}
+	err = db.QueryRow("SELECT coin FROM `account` WHERE id=?", userId).Scan(&coin)
+	return
+}
+
 // ServeWs 从 http 请求升级到 WebSocket 连接，并启动一个新的客户端进行处理。
 //
 // Args:
@@ -243,7 +378,8 @@ func (c *Client) Ping() {
 //
 // Behavior:
 // - 如果升级连接失败，将记录错误日志并返回。
-// - 如果成功升级连接，将根据客户端的 cookie 设置客户端的用户 ID 和用户名，并启动读取和发送心跳的 goroutine。
+// - 如果成功升级连接，将根据客户端的 cookie 设置客户端的用户 ID 和用户名，从 account 表读取金币余额，并启动读取和发送心跳的 goroutine。
+// - 如果玩家有进行中的牌局，将连接接到原来的座位上并发送牌局快照，见 resume。
 // - 如果客户端的用户 ID 和用户名为空，则记录错误日志并关闭连接。
 //
 // Concurrency Safety: ServeWs 函数本身是并发安全的，但是在函数内部创建的客户端实例不是并发安全的，应注意。
@@ -281,6 +417,12 @@ func ServeWs(w http.ResponseWriter, r *http.Request) {
 	if userId != 0 && username != "" {
 		client.UserInfo.UserId = UserId(userId)
 		client.UserInfo.Username = username
+		if client.UserInfo.Coin, err = loadCoin(client.UserInfo.UserId); err != nil {
+			logs.Error("load user [%d] coin err: %v", userId, err)
+		}
+		if c := resume(client.UserInfo.UserId, conn); c != nil {
+			client = c
+		}
 		go client.readPump()
 		go client.Ping()
 		return
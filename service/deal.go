@@ -0,0 +1,83 @@
+package service
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+	"math/rand"
+	"time"
+)
+
+// ErrInvalidDeal 表示预先安排的牌与玩法不符：人数、张数不对，或者不是恰好一整副牌。
+var ErrInvalidDeal = errors.New("invalid deal")
+
+// Deal 是一局发出的牌。
+//
+// Hands 按发牌顺序排列：从本局第一个叫分的玩家开始，沿出牌顺序依次排列，
+// 每手牌的张数等于玩法规定的张数；Bottom 是底牌。
+type Deal struct {
+	Hands  [][]int
+	Bottom []int
+}
+
+// NewDeal 用种子 seed 洗一副 rules 规定的牌并发好，相同的玩法和种子总是得到相同的结果。
+// 洗好的牌从末尾开始，按发牌顺序每人一张轮流发出，剩下的作为底牌。
+func NewDeal(rules common.RuleSet, seed int64) Deal {
+	r := rand.New(rand.NewSource(seed))
+	pokers := make([]int, rules.DeckSize())
+	for i := range pokers {
+		pokers[i] = i
+	}
+	i := len(pokers)
+	for i > 0 {
+		randIndex := r.Intn(i)
+		pokers[i-1], pokers[randIndex] = pokers[randIndex], pokers[i-1]
+		i--
+	}
+	deal := Deal{Hands: make([][]int, rules.Players())}
+	for i := 0; i < rules.HandSize(); i++ {
+		for seat := range deal.Hands {
+			deal.Hands[seat] = append(deal.Hands[seat], pokers[len(pokers)-1])
+			pokers = pokers[:len(pokers)-1]
+		}
+	}
+	deal.Bottom = pokers
+	return deal
+}
+
+// validate 检查发出的牌是否符合玩法 rules：人数和每手张数正确，所有的牌恰好组成一整副牌。
+func (d Deal) validate(rules common.RuleSet) error {
+	if len(d.Hands) != rules.Players() {
+		return ErrInvalidDeal
+	}
+	seen := make(map[int]bool, rules.DeckSize())
+	for _, pokers := range append(append([][]int(nil), d.Hands...), d.Bottom) {
+		for _, poker := range pokers {
+			if poker < 0 || poker >= rules.DeckSize() || seen[poker] {
+				return ErrInvalidDeal
+			}
+			seen[poker] = true
+		}
+	}
+	for _, hand := range d.Hands {
+		if len(hand) != rules.HandSize() {
+			return ErrInvalidDeal
+		}
+	}
+	if len(seen) != rules.DeckSize() {
+		return ErrInvalidDeal
+	}
+	return nil
+}
+
+// newSeed 使用 crypto/rand 生成洗牌的种子，失败时退回到当前时间。
+func newSeed() int64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		logs.Error("read crypto seed err: %v", err)
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
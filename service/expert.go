@@ -0,0 +1,199 @@
+package service
+
+import (
+	"landlord/common"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultExpertSamples 是专家机器人每次出牌最多抽样的次数。
+	defaultExpertSamples = 400
+
+	// defaultExpertTimeout 是专家机器人每次出牌最多的思考时间。
+	defaultExpertTimeout = time.Second
+)
+
+// ExpertStrategy 是专家机器人：叫分与困难机器人相同，出牌时使用蒙特卡洛模拟。
+//
+// 每次出牌时，先把看不到的牌（整副牌去掉自己的手牌、已经打出的牌和已知在地主手里的底牌）
+// 按各家剩余张数随机分给其他玩家，得到一种可能的牌局；再对每种候选出法，
+// 让所有玩家按普通机器人的策略把这局牌打完，统计自己一方获胜的次数。
+// 反复抽样直到达到 Samples 次或超过 Timeout，选择获胜次数最多的出法，
+// 次数相同时选择普通机器人会出的牌。
+type ExpertStrategy struct {
+	Samples int           // 抽样次数上限，为 0 时使用 defaultExpertSamples
+	Timeout time.Duration // 思考时间上限，为 0 时使用 defaultExpertTimeout
+}
+
+func (ExpertStrategy) Bid(view GameView) int {
+	return planCallScore(view.Rules, view.HandPokers, view.MaxCallScore, RobotHard)
+}
+
+func (s ExpertStrategy) Play(view GameView) []int {
+	candidates := expertCandidates(view)
+	for _, move := range candidates {
+		if len(move) == len(view.HandPokers) {
+			return move
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	samples, timeout := s.Samples, s.Timeout
+	if samples <= 0 {
+		samples = defaultExpertSamples
+	}
+	if timeout <= 0 {
+		timeout = defaultExpertTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	var (
+		lock    sync.Mutex
+		wg      sync.WaitGroup
+		started int64
+	)
+	wins := make([]int, len(candidates))
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			local := make([]int, len(candidates))
+			for atomic.AddInt64(&started, 1) <= int64(samples) && time.Now().Before(deadline) {
+				hands := sampleHands(view, rnd)
+				if hands == nil {
+					break
+				}
+				for i, move := range candidates {
+					if playout(view, hands, move) {
+						local[i]++
+					}
+				}
+			}
+			lock.Lock()
+			for i, n := range local {
+				wins[i] += n
+			}
+			lock.Unlock()
+		}(rand.Int63())
+	}
+	wg.Wait()
+
+	best := 0
+	for i, n := range wins {
+		if n > wins[best] {
+			best = i
+		}
+	}
+	return candidates[best]
+}
+
+// expertCandidates 列出专家机器人需要模拟的出法，普通机器人会出的牌排在第一个。
+// 跟牌时列出所有合法的出法（包括不出）；自由出牌时只列出拆牌结果中的各手牌和困难机器人会出的牌。
+func expertCandidates(view GameView) (candidates [][]int) {
+	seen := make(map[string]bool)
+	add := func(moves ...[]int) {
+		for _, move := range moves {
+			key := common.SortStr(common.ToPokers(move))
+			if !seen[key] {
+				seen[key] = true
+				candidates = append(candidates, move)
+			}
+		}
+	}
+	add(planShotPoker(view))
+	if len(view.LastShot) > 0 {
+		add(common.LegalMovesWith(view.Rules, view.HandPokers, view.LastShot)...)
+		return
+	}
+	add(HardStrategy{}.Play(view))
+	add(common.Decompose(view.Rules, view.HandPokers)...)
+	return
+}
+
+// sampleHands 随机生成一种与已知信息一致的牌局，返回从自己开始每个玩家的手牌。
+// 自己不是地主时，底牌中还没打出的牌一定在地主手里；其余看不到的牌随机分给其他玩家。
+// 已知信息互相矛盾时返回 nil。
+func sampleHands(view GameView, rnd *rand.Rand) [][]int {
+	landlord := -1
+	for i, player := range view.Players {
+		if i > 0 && player.Role == RoleLandlord {
+			landlord = i
+		}
+	}
+	var bottom []int
+	if landlord > 0 {
+		bottom = removePokers(view.BottomPokers, view.ShotPokers)
+	}
+	pool := removePokers(view.unseenPokers(), bottom)
+	rnd.Shuffle(len(pool), func(i, j int) {
+		pool[i], pool[j] = pool[j], pool[i]
+	})
+
+	hands := make([][]int, len(view.Players))
+	hands[0] = view.HandPokers
+	for i := 1; i < len(view.Players); i++ {
+		need := view.Players[i].Cards
+		if i == landlord {
+			need -= len(bottom)
+			hands[i] = append(hands[i], bottom...)
+		}
+		if need < 0 || need > len(pool) {
+			return nil
+		}
+		hands[i] = append(hands[i], pool[:need]...)
+		pool = pool[need:]
+	}
+	if len(pool) > 0 {
+		return nil
+	}
+	return hands
+}
+
+// playout 在手牌为 hands 的牌局中，自己出 move 之后让所有玩家按 planShotPoker 把牌打完，
+// 返回自己一方是否获胜。不修改 hands。
+func playout(view GameView, hands [][]int, move []int) bool {
+	n := len(hands)
+	hands = append([][]int(nil), hands...)
+	hands[0] = removePokers(hands[0], move)
+	last, lastIndex := view.LastShot, view.LastShotIndex
+	if len(move) > 0 {
+		last, lastIndex = move, 0
+	}
+	if len(hands[0]) == 0 {
+		return true
+	}
+	for turn := 0; ; {
+		turn = (turn + 1) % n
+		if turn == lastIndex {
+			last, lastIndex = nil, -1
+		}
+		sub := GameView{
+			Rules:         view.Rules,
+			HandPokers:    hands[turn],
+			Players:       make([]PlayerView, n),
+			LastShot:      last,
+			LastShotIndex: -1,
+		}
+		for i := range sub.Players {
+			player := view.Players[(turn+i)%n]
+			player.Cards = len(hands[(turn+i)%n])
+			sub.Players[i] = player
+		}
+		if lastIndex >= 0 {
+			sub.LastShotIndex = (lastIndex - turn + n) % n
+		}
+		if shot := planShotPoker(sub); len(shot) > 0 {
+			hands[turn] = removePokers(hands[turn], shot)
+			last, lastIndex = shot, turn
+		}
+		if len(hands[turn]) == 0 {
+			return view.Players[turn].Role == view.Players[0].Role
+		}
+	}
+}
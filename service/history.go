@@ -0,0 +1,149 @@
+package service
+
+import (
+	"database/sql"
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+	"time"
+)
+
+// coinDeltas 计算一局结束时每个玩家输赢的金币，winner 是最先出完牌的玩家，winner 所在的一方获胜。
+// 每个农民与地主之间输赢 入场费 * 叫分 * 倍数，地主输赢的是所有农民的总和，即对每个农民加倍；
+// 此外每个玩家都要扣除一份入场费。
+func (table *Table) coinDeltas(winner *Client) map[UserId]int {
+	game := table.GameManage
+	fee := table.Creator.Room.EntranceFee
+	stake := fee * game.MaxCallScore * game.Multiple
+	deltas := make(map[UserId]int, len(table.TableClients))
+	for _, c := range table.TableClients {
+		deltas[c.UserInfo.UserId] -= fee
+		if c.UserInfo.Role == RoleLandlord {
+			continue
+		}
+		delta := stake
+		if winner.UserInfo.Role == RoleLandlord {
+			delta = -stake
+		}
+		deltas[c.UserInfo.UserId] += delta
+		if game.MaxCallScoreTurn != nil {
+			deltas[game.MaxCallScoreTurn.UserInfo.UserId] -= delta
+		}
+	}
+	return deltas
+}
+
+// saveGame 在一个事务中保存结束的一局并结算金币，winner 是最先出完牌的玩家，deltas 是 coinDeltas 的结果。
+// 调用方需持有牌桌的锁。
+//
+// 事务中依次写入：
+//   - 本局录像，见 Replay；
+//   - game 表：玩法、录像 ID、地主叫分、倍数、炸弹总数、获胜的角色和对局时长（秒）；
+//   - game_player 表：每个玩家的角色、叫分（没有轮到叫分时为 -1）、打出的炸弹数、是否获胜和输赢的金币，
+//     机器人也会记录，但 robot 为 1，不计入玩家统计；
+//   - 真人玩家的 account.coin，以及 coin_ledger 表中入场费和输赢各一条流水，记录变动后的余额。
+//
+// 事务提交后更新玩家的 UserInfo.Coin；事务失败时全部回滚，玩家的金币不变。
+// 没有配置数据库时（例如自我对局模拟）只更新 UserInfo.Coin。
+func (table *Table) saveGame(winner *Client, deltas map[UserId]int) {
+	db := common.GameConfInfo.Db
+	if db == nil {
+		for _, c := range table.TableClients {
+			c.UserInfo.Coin += deltas[c.UserInfo.UserId]
+		}
+		return
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		logs.Error("save game begin err: %v", err)
+		return
+	}
+	gameId, balances, err := table.insertGame(tx, winner, deltas)
+	if err != nil {
+		logs.Error("save game of table[%d] err: %v", table.TableId, err)
+		if err = tx.Rollback(); err != nil {
+			logs.Error("save game rollback err: %v", err)
+		}
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		logs.Error("save game commit err: %v", err)
+		return
+	}
+	for _, c := range table.TableClients {
+		if balance, ok := balances[c.UserInfo.UserId]; ok {
+			c.UserInfo.Coin = balance
+		} else {
+			c.UserInfo.Coin += deltas[c.UserInfo.UserId]
+		}
+	}
+	logs.Debug("table[%d] game saved, id %d", table.TableId, gameId)
+}
+
+// insertGame 执行 saveGame 事务中的写入，返回对局 ID 和真人玩家结算后的余额。
+func (table *Table) insertGame(tx *sql.Tx, winner *Client, deltas map[UserId]int) (gameId int64, balances map[UserId]int, err error) {
+	game := table.GameManage
+	var replayId int64
+	if game.Replay != nil {
+		if err = game.Replay.insert(tx); err != nil {
+			return
+		}
+		replayId = game.Replay.Id
+	}
+	bombs := 0
+	for _, n := range game.Bombs {
+		bombs += n
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	res, err := tx.Exec("INSERT INTO `game` (replay_id, table_id, rules, call_score, multiple, bombs, winner_role, duration, created_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		replayId, table.TableId, table.Rules.Name(), game.MaxCallScore, game.Multiple, bombs, winner.UserInfo.Role,
+		int(time.Since(game.StartTime)/time.Second), game.StartTime.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return
+	}
+	if gameId, err = res.LastInsertId(); err != nil {
+		return
+	}
+
+	fee := table.Creator.Room.EntranceFee
+	balances = make(map[UserId]int, len(table.TableClients))
+	for _, c := range table.TableClients {
+		userId := c.UserInfo.UserId
+		callScore, ok := game.CallScores[userId]
+		if !ok {
+			callScore = -1
+		}
+		_, err = tx.Exec("INSERT INTO `game_player` (game_id, user_id, robot, role, call_score, bombs, win, coin) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			gameId, userId, c.IsRobot, c.UserInfo.Role, callScore, game.Bombs[userId], c.UserInfo.Role == winner.UserInfo.Role, deltas[userId])
+		if err != nil {
+			return
+		}
+		if c.IsRobot {
+			continue
+		}
+
+		if _, err = tx.Exec("UPDATE `account` SET coin=coin+?, updated_date=? WHERE id=?", deltas[userId], now, userId); err != nil {
+			return
+		}
+		var balance int
+		if err = tx.QueryRow("SELECT coin FROM `account` WHERE id=?", userId).Scan(&balance); err != nil {
+			return
+		}
+		balances[userId] = balance
+		ledger := []struct {
+			reason  string
+			amount  int
+			balance int
+		}{
+			{"entrance_fee", -fee, balance - deltas[userId] - fee},
+			{"game", deltas[userId] + fee, balance},
+		}
+		for _, entry := range ledger {
+			_, err = tx.Exec("INSERT INTO `coin_ledger` (user_id, game_id, reason, amount, balance, created_date) VALUES (?, ?, ?, ?, ?, ?)",
+				userId, gameId, entry.reason, entry.amount, entry.balance, now)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
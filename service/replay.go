@@ -0,0 +1,269 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// replayMaxGap 是播放录像时两条消息之间最长的等待时间（1 倍速时），跳过玩家长时间的思考。
+	replayMaxGap = 3 * time.Second
+
+	// replayMinSpeed 和 replayMaxSpeed 是播放录像速度的范围。
+	replayMinSpeed = 0.25
+	replayMaxSpeed = 8
+)
+
+// Replay 是一局牌的录像，按发生顺序记录发牌、叫分、亮底牌、出牌（包括不出）和结算时发出的消息。
+// 录像在一局结束时与对局记录一起写入数据库，只追加不修改，见 saveGame。
+//
+// - Id: 录像 ID，保存后由数据库生成。
+// - TableId: 牌桌 ID。
+// - Rules: 玩法名称。
+// - Seed: 洗牌使用的种子，使用预先安排的发牌时为 0。
+// - Players: 按座位顺序排列的玩家，与 ResJoinTable 中的顺序一致。
+// - CreatedDate: 开始录制的时间。
+// - Events: 按顺序记录的消息。
+type Replay struct {
+	Id          int64          `json:"id"`
+	TableId     TableId        `json:"table_id"`
+	Rules       string         `json:"rules"`
+	Seed        int64          `json:"seed"`
+	Players     []ReplayPlayer `json:"players"`
+	CreatedDate string         `json:"created_date"`
+	Events      []ReplayEvent  `json:"events"`
+	start       time.Time
+}
+
+// ReplayPlayer 是录像中的一个玩家。
+type ReplayPlayer struct {
+	UserId   UserId `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// ReplayEvent 是录像中的一条消息。
+// At 是距离发牌的毫秒数；UserId 是只发给某个玩家的消息（发牌、结算）的接收者，广播消息为 0；
+// Msg 是发出的消息，记录时即序列化，之后牌桌状态的变化不会影响录像。
+type ReplayEvent struct {
+	At     int64           `json:"at"`
+	UserId UserId          `json:"user_id"`
+	Msg    json.RawMessage `json:"msg"`
+}
+
+// replayPlayback 是客户端正在播放的录像，speed 可以在播放过程中调整，关闭 stop 时停止播放。
+type replayPlayback struct {
+	lock  sync.Mutex
+	speed float64
+	stop  chan struct{}
+}
+
+// newReplay 为牌桌上新的一局创建录像，调用方需持有牌桌的锁。
+func (table *Table) newReplay() *Replay {
+	now := time.Now()
+	replay := &Replay{
+		TableId:     table.TableId,
+		Rules:       table.Rules.Name(),
+		Seed:        table.GameManage.Seed,
+		CreatedDate: now.Format("2006-01-02 15:04:05"),
+		start:       now,
+	}
+	for _, current := range table.players(nil) {
+		replay.Players = append(replay.Players, ReplayPlayer{UserId: current.UserInfo.UserId, Username: current.UserInfo.Username})
+	}
+	return replay
+}
+
+// record 将发给玩家 userId 的消息记入本局录像，userId 为 0 表示广播消息。
+func (table *Table) record(userId UserId, msg []interface{}) {
+	replay := table.GameManage.Replay
+	if replay == nil {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		logs.Error("table[%d] record replay msg %v err: %v", table.TableId, msg, err)
+		return
+	}
+	replay.Events = append(replay.Events, ReplayEvent{
+		At:     int64(time.Since(replay.start) / time.Millisecond),
+		UserId: userId,
+		Msg:    data,
+	})
+}
+
+// insert 在事务 tx 中将录像写入数据库，并设置录像 ID。
+func (replay *Replay) insert(tx *sql.Tx) error {
+	players, err := json.Marshal(replay.Players)
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec("INSERT INTO `replay` (table_id, rules, seed, players, created_date) VALUES (?, ?, ?, ?, ?)",
+		replay.TableId, replay.Rules, replay.Seed, string(players), replay.CreatedDate)
+	if err != nil {
+		return err
+	}
+	if replay.Id, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	for seq, event := range replay.Events {
+		_, err = tx.Exec("INSERT INTO `replay_event` (replay_id, seq, at, user_id, msg) VALUES (?, ?, ?, ?, ?)",
+			replay.Id, seq, event.At, event.UserId, string(event.Msg))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadReplay 从数据库读取录像，录像不存在时返回 sql.ErrNoRows。
+func loadReplay(id int64) (*Replay, error) {
+	db := common.GameConfInfo.Db
+	if db == nil {
+		return nil, sql.ErrNoRows
+	}
+	replay := &Replay{Id: id}
+	var players string
+	err := db.QueryRow("SELECT table_id, rules, seed, players, created_date FROM `replay` WHERE id=?", id).
+		Scan(&replay.TableId, &replay.Rules, &replay.Seed, &players, &replay.CreatedDate)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(players), &replay.Players); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT at, user_id, msg FROM `replay_event` WHERE replay_id=? ORDER BY seq", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event ReplayEvent
+		var msg string
+		if err = rows.Scan(&event.At, &event.UserId, &msg); err != nil {
+			return nil, err
+		}
+		event.Msg = json.RawMessage(msg)
+		replay.Events = append(replay.Events, event)
+	}
+	return replay, rows.Err()
+}
+
+// ServeReplay 处理 /replay/{id} 请求，以 JSON 格式返回录像。
+func ServeReplay(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/replay/"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	replay, err := loadReplay(id)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logs.Error("load replay [%d] err: %v", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err = json.NewEncoder(w).Encode(replay); err != nil {
+		logs.Error("write replay [%d] err: %v", id, err)
+	}
+}
+
+// playReplay 开始向客户端播放录像 id，之前正在播放的录像会被停止。
+// 观看视角 pov 为 0 时，如果客户端参与了这局牌就使用自己的视角，否则使用第一个玩家的视角。
+// 先回复 ResReplay 和 ResJoinTable，然后在新的 goroutine 中按录制时的节奏推送广播消息和视角玩家收到的消息。
+func (c *Client) playReplay(id int64, speed float64, pov UserId) {
+	c.stopReplay()
+	replay, err := loadReplay(id)
+	if err != nil {
+		logs.Error("user [%d] play replay [%d] err: %v", c.UserInfo.UserId, id, err)
+		c.sendMsg([]interface{}{common.ResReplay, id, 0})
+		return
+	}
+	if pov == 0 && len(replay.Players) > 0 {
+		pov = replay.Players[0].UserId
+		for _, player := range replay.Players {
+			if player.UserId == c.UserInfo.UserId {
+				pov = c.UserInfo.UserId
+			}
+		}
+	}
+	tableUsers := make([][2]interface{}, 0, len(replay.Players))
+	for _, player := range replay.Players {
+		tableUsers = append(tableUsers, [2]interface{}{player.UserId, player.Username})
+	}
+	c.sendMsg([]interface{}{common.ResReplay, id, pov})
+	c.sendMsg([]interface{}{common.ResJoinTable, replay.TableId, tableUsers})
+
+	playback := &replayPlayback{stop: make(chan struct{})}
+	playback.setSpeed(speed)
+	c.playback = playback
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				logs.Warn("play replay err: %v", err)
+			}
+		}()
+		var last int64
+		for _, event := range replay.Events {
+			if event.UserId != 0 && event.UserId != pov {
+				continue
+			}
+			gap := time.Duration(event.At-last) * time.Millisecond
+			if gap > replayMaxGap {
+				gap = replayMaxGap
+			}
+			last = event.At
+			select {
+			case <-playback.stop:
+				return
+			case <-time.After(time.Duration(float64(gap) / playback.getSpeed())):
+			}
+			var msg []interface{}
+			if err := json.Unmarshal(event.Msg, &msg); err != nil {
+				logs.Error("unmarshal replay [%d] msg err: %v", id, err)
+				return
+			}
+			c.sendMsg(msg)
+		}
+	}()
+}
+
+// stopReplay 停止客户端正在播放的录像。
+func (c *Client) stopReplay() {
+	if c.playback != nil {
+		close(c.playback.stop)
+		c.playback = nil
+	}
+}
+
+// setSpeed 设置播放速度，超出范围时取最近的边界值，不是正数时为 1 倍速。
+func (p *replayPlayback) setSpeed(speed float64) {
+	switch {
+	case speed <= 0:
+		speed = 1
+	case speed < replayMinSpeed:
+		speed = replayMinSpeed
+	case speed > replayMaxSpeed:
+		speed = replayMaxSpeed
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.speed = speed
+}
+
+// getSpeed 返回当前的播放速度。
+func (p *replayPlayback) getSpeed() float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.speed
+}
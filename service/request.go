@@ -5,7 +5,24 @@ import (
 	"landlord/common"
 )
 
+// requestError 是拒绝请求的原因，Code 是 common 中 ErrCode 开头的错误代码，Reason 可以直接展示给玩家。
+type requestError struct {
+	Code   int
+	Reason string
+}
+
+// playError 将出牌校验的错误转换为 requestError。
+func playError(err error) *requestError {
+	if err == common.ErrNotBigger {
+		return &requestError{common.ErrCodeNotBigger, "出的牌没有大过上家"}
+	}
+	return &requestError{common.ErrCodeInvalidHand, "不是合法的牌型"}
+}
+
 // wsRequest 处理 websocket 请求。
+// 被拒绝的请求会以 ResError 回复发出请求的客户端，见 Client.sendError。
+// 观战者不能叫分、出牌等操作牌局，进入房间或者牌桌时停止观战。
+// 牌桌上的请求只在牌桌的状态允许时处理，见 stateRequests。
 func wsRequest(data []interface{}, client *Client) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -20,6 +37,37 @@ func wsRequest(data []interface{}, client *Client) {
 		req = int(r)
 	}
 	switch req {
+	case common.ReqDealPoker, common.ReqCallScore, common.ReqRob, common.ReqShotPoker, common.ReqPass, common.ReqHint, common.ReqChat, common.ReqRestart, common.ReqAutoPlay:
+		if client.watching != nil {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "观战时不能操作牌局"})
+			return
+		}
+		if client.Table == nil {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "还没有加入牌桌"})
+			return
+		}
+		if !client.Table.allows(req) {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "牌桌现在的状态不能进行该操作"})
+			return
+		}
+	case common.ReqJoinRoom, common.ReqNewTable, common.ReqJoinTable, common.ReqWatchTable:
+		if client.inGame() {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "牌局还没有结束"})
+			return
+		}
+		client.stopWatching()
+		client.stopReplay()
+		if req != common.ReqWatchTable && !client.leaveTable() {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "牌局还没有结束"})
+			return
+		}
+	case common.ReqReplay:
+		if client.Table != nil || client.watching != nil {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "在牌桌上或观战时不能播放录像"})
+			return
+		}
+	}
+	switch req {
 	case common.ReqCheat:
 		if len(data) < 2 {
 			logs.Error("user [%d] request ReqCheat ,but missing user id", client.UserInfo.UserId)
@@ -33,11 +81,16 @@ func wsRequest(data []interface{}, client *Client) {
 		client.sendMsg([]interface{}{common.ResRoomList})
 
 	case common.ReqTableList:
+		if client.Room == nil {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "还没有进入房间"})
+			return
+		}
 		client.sendRoomTables()
 
 	case common.ReqJoinRoom:
 		if len(data) < 2 {
 			logs.Error("user [%d] request join room ,but missing room id", client.UserInfo.UserId)
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "缺少房间号"})
 			return
 		}
 		var roomId int
@@ -47,22 +100,29 @@ func wsRequest(data []interface{}, client *Client) {
 		roomManager.Lock.RLock()
 		defer roomManager.Lock.RUnlock()
 		if room, ok := roomManager.Rooms[roomId]; ok {
-			client.Room = room
-			res := make([][2]int, 0)
-			for _, table := range client.Room.Tables {
-				if len(table.TableClients) < 3 {
-					res = append(res, [2]int{int(table.TableId), len(table.TableClients)})
-				}
+			if !client.allowRoom(room) {
+				return
 			}
-			client.sendMsg([]interface{}{common.ResJoinRoom, res})
+			client.Room = room
+			client.sendMsg([]interface{}{common.ResJoinRoom, room.tables()})
+		} else {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "房间不存在"})
 		}
 
 	case common.ReqNewTable:
+		if client.Room == nil {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "还没有进入房间"})
+			return
+		}
+		if !client.allowRoom(client.Room) {
+			return
+		}
 		table := client.Room.newTable(client)
 		table.joinTable(client)
 
 	case common.ReqJoinTable:
 		if len(data) < 2 {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "缺少牌桌号"})
 			return
 		}
 		var tableId TableId
@@ -70,6 +130,10 @@ func wsRequest(data []interface{}, client *Client) {
 			tableId = TableId(id)
 		}
 		if client.Room == nil {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "还没有进入房间"})
+			return
+		}
+		if !client.allowRoom(client.Room) {
 			return
 		}
 		client.Room.Lock.RLock()
@@ -79,121 +143,184 @@ func wsRequest(data []interface{}, client *Client) {
 			table.joinTable(client)
 		}
 		client.sendRoomTables()
-	case common.ReqDealPoker:
-		if client.Table.State == GameEnd {
-			client.Ready = true
-		}
-	case common.ReqCallScore:
-		logs.Debug("[%v] ReqCallScore %v", client.UserInfo.Username, data)
-		client.Table.Lock.Lock()
-		defer client.Table.Lock.Unlock()
 
-		if client.Table.State != GameCallScore {
-			logs.Debug("game call score at run time ,%v", client.Table.State)
+	case common.ReqWatchTable:
+		if len(data) < 2 {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "缺少牌桌号"})
 			return
 		}
-		if client.Table.GameManage.Turn == client || client.Table.GameManage.FirstCallScore == client {
-			client.Table.GameManage.Turn = client.Next
-		} else {
-			logs.Debug("user [%v] call score turn err ")
+		var tableId TableId
+		if id, ok := data[1].(float64); ok {
+			tableId = TableId(id)
 		}
+		if client.Room == nil {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "还没有进入房间"})
+			return
+		}
+		if client.Table != nil {
+			client.sendError(req, &requestError{common.ErrCodeWrongState, "已经在牌桌上，不能观战"})
+			return
+		}
+		client.Room.Lock.RLock()
+		table, ok := client.Room.Tables[tableId]
+		client.Room.Lock.RUnlock()
+		if !ok {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "牌桌不存在"})
+			return
+		}
+		table.Lock.Lock()
+		defer table.Lock.Unlock()
+
+		if err := table.watch(client); err != nil {
+			client.sendError(req, err)
+		}
+
+	case common.ReqDealPoker:
+		client.Ready = true
+	case common.ReqCallScore:
+		logs.Debug("[%v] ReqCallScore %v", client.UserInfo.Username, data)
 		if len(data) < 2 {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "缺少叫分"})
 			return
 		}
 		var score int
 		if s, ok := data[1].(float64); ok {
 			score = int(s)
 		}
+		client.Table.Lock.Lock()
+		defer client.Table.Lock.Unlock()
 
-		if score > 0 && score < client.Table.GameManage.MaxCallScore || score > 3 {
-			logs.Error("player[%d] call score[%d] cheat", client.UserInfo.UserId, score)
+		if err := client.Table.callScore(client, score); err != nil {
+			client.sendError(req, err)
 			return
 		}
-		if score > client.Table.GameManage.MaxCallScore {
-			client.Table.GameManage.MaxCallScore = score
-			client.Table.GameManage.MaxCallScoreTurn = client
+		client.timeouts = 0
+
+	case common.ReqRob:
+		logs.Debug("[%v] ReqRob %v", client.UserInfo.Username, data)
+		if len(data) < 2 {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "缺少是否抢地主"})
+			return
 		}
-		client.IsCalled = true
-		callEnd := score == 3 || client.Table.allCalled()
-		userCall := []interface{}{common.ResCallScore, client.UserInfo.UserId, score, callEnd}
-		for _, c := range client.Table.TableClients {
-			c.sendMsg(userCall)
+		rob, ok := data[1].(bool)
+		if !ok {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "是否抢地主格式错误"})
+			return
 		}
-		if callEnd {
-			logs.Debug("call score end")
-			client.Table.callEnd()
+		client.Table.Lock.Lock()
+		defer client.Table.Lock.Unlock()
+
+		if err := client.Table.rob(client, rob); err != nil {
+			client.sendError(req, err)
+			return
 		}
+		client.timeouts = 0
+
 	case common.ReqShotPoker:
 		logs.Debug("user [%v] ReqShotPoker %v", client.UserInfo.Username, data)
+		var pokers []interface{}
+		if len(data) > 1 {
+			pokers, _ = data[1].([]interface{})
+		}
+		if pokers == nil {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "缺少出的牌"})
+			return
+		}
+		shotPokers := make([]int, 0, len(pokers))
+		for _, item := range pokers {
+			i, ok := item.(float64)
+			if !ok {
+				client.sendError(req, &requestError{common.ErrCodeBadRequest, "牌的编号格式错误"})
+				return
+			}
+			shotPokers = append(shotPokers, int(i))
+		}
+		client.Table.Lock.Lock()
+		defer client.Table.Lock.Unlock()
+
+		var err *requestError
+		if len(shotPokers) == 0 {
+			// 兼容用空数组表示不出的客户端
+			err = client.Table.pass(client)
+		} else {
+			err = client.Table.shotPoker(client, shotPokers)
+		}
+		if err != nil {
+			client.sendError(req, err)
+			return
+		}
+		client.timeouts = 0
+
+	case common.ReqPass:
+		logs.Debug("user [%v] ReqPass", client.UserInfo.Username)
 		client.Table.Lock.Lock()
-		defer func() {
-			client.Table.GameManage.Turn = client.Next
-			client.Table.Lock.Unlock()
-		}()
+		defer client.Table.Lock.Unlock()
+
+		if err := client.Table.pass(client); err != nil {
+			client.sendError(req, err)
+			return
+		}
+		client.timeouts = 0
+
+	case common.ReqAutoPlay:
+		if len(data) < 2 {
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "缺少是否托管"})
+			return
+		}
+		on, _ := data[1].(bool)
+		client.Table.Lock.Lock()
+		defer client.Table.Lock.Unlock()
+
+		if client.IsRobot || client.autoPlay == on {
+			return
+		}
+		client.Table.setAutoPlay(client, on)
+		if client.Table.turnClient() == client {
+			client.Table.startTurn()
+		}
+
+	case common.ReqHint:
+		client.Table.Lock.Lock()
+		defer client.Table.Lock.Unlock()
 
 		if client.Table.GameManage.Turn != client {
-			logs.Error("shot poker err,not your [%d] turn .[%d]", client.UserInfo.UserId, client.Table.GameManage.Turn.UserInfo.UserId)
+			logs.Debug("user [%v] request hint out of turn", client.UserInfo.Username)
+			client.sendError(req, &requestError{common.ErrCodeNotYourTurn, "还没有轮到你出牌"})
 			return
 		}
-		if len(data) > 1 {
-			if pokers, ok := data[1].([]interface{}); ok {
-				shotPokers := make([]int, 0, len(pokers))
-				for _, item := range pokers {
-					if i, ok := item.(float64); ok {
-						poker := int(i)
-						inHand := false
-						for _, handPoker := range client.HandPokers {
-							if handPoker == poker {
-								inHand = true
-								break
-							}
-						}
-						if inHand {
-							shotPokers = append(shotPokers, poker)
-						} else {
-							logs.Warn("player[%d] play non-exist poker", client.UserInfo.UserId)
-							res := []interface{}{common.ResShotPoker, client.UserInfo.UserId, []int{}}
-							for _, c := range client.Table.TableClients {
-								c.sendMsg(res)
-							}
-							return
-						}
-					}
-				}
-				if len(shotPokers) > 0 {
-					compareRes, isMulti := common.ComparePoker(client.Table.GameManage.LastShotPoker, shotPokers)
-					if client.Table.GameManage.LastShotClient != client && compareRes < 1 {
-						logs.Warn("player[%d] shot poker %v small than last shot poker %v ", client.UserInfo.UserId, shotPokers, client.Table.GameManage.LastShotPoker)
-						res := []interface{}{common.ResShotPoker, client.UserInfo.UserId, []int{}}
-						for _, c := range client.Table.TableClients {
-							c.sendMsg(res)
-						}
-						return
-					}
-					if isMulti {
-						client.Table.GameManage.Multiple *= 2
-					}
-					client.Table.GameManage.LastShotClient = client
-					client.Table.GameManage.LastShotPoker = shotPokers
-					for _, shotPoker := range shotPokers {
-						for i, poker := range client.HandPokers {
-							if shotPoker == poker {
-								copy(client.HandPokers[i:], client.HandPokers[i+1:])
-								client.HandPokers = client.HandPokers[:len(client.HandPokers)-1]
-								break
-							}
-						}
-					}
-				}
-				res := []interface{}{common.ResShotPoker, client.UserInfo.UserId, shotPokers}
-				for _, c := range client.Table.TableClients {
-					c.sendMsg(res)
-				}
-				if len(client.HandPokers) == 0 {
-					client.Table.gameOver(client)
-				}
+		client.sendMsg([]interface{}{common.ResHint, client.nextHint()})
+
+	case common.ReqReplay:
+		if len(data) < 2 {
+			logs.Error("user [%d] request replay ,but missing replay id", client.UserInfo.UserId)
+			client.sendError(req, &requestError{common.ErrCodeBadRequest, "缺少录像 ID"})
+			return
+		}
+		var id int64
+		if i, ok := data[1].(float64); ok {
+			id = int64(i)
+		}
+		speed := 1.0
+		if len(data) > 2 {
+			if s, ok := data[2].(float64); ok {
+				speed = s
+			}
+		}
+		var pov UserId
+		if len(data) > 3 {
+			if u, ok := data[3].(float64); ok {
+				pov = UserId(u)
 			}
 		}
+		client.playReplay(id, speed, pov)
+
+	case common.ReqReplaySpeed:
+		if len(data) < 2 || client.playback == nil {
+			return
+		}
+		if s, ok := data[1].(float64); ok {
+			client.playback.setSpeed(s)
+		}
 
 		//case common.ReqGameOver:
 	case common.ReqChat:
@@ -207,15 +334,11 @@ func wsRequest(data []interface{}, client *Client) {
 		client.Table.Lock.Lock()
 		defer client.Table.Lock.Unlock()
 
-		if client.Table.State == GameEnd {
-			client.Ready = true
-			for _, c := range client.Table.TableClients {
-				if c.Ready == false {
-					return
-				}
-			}
-			logs.Debug("restart")
-			client.Table.reset()
+		if client.Table.State != GameEnd {
+			// 在加锁之前牌桌已经重新开始
+			return
 		}
+		client.Ready = true
+		client.Table.restart()
 	}
 }
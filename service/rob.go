@@ -0,0 +1,72 @@
+package service
+
+import (
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+)
+
+// rob 处理抢地主玩法中玩家 c 叫地主或抢地主，rob 为 false 表示不叫或不抢，调用方需持有牌桌的锁。
+// 从第一个叫分的玩家开始，每个玩家依次选择一次：还没有人叫地主时是叫地主，之后是抢地主，叫地主的玩家的底分为 1 分，
+// 每抢一次 GameManage.Multiple 翻倍。所有人都选择过后，如果叫地主之后有人抢过，叫地主的玩家还可以最后再抢一次。
+// 最后一个叫到或抢到的玩家当地主，没有人叫地主时按房间的 AllPass 处理，见 callEnd。
+// 不是抢地主的牌桌、不在叫地主阶段或者没有轮到 c 时返回拒绝的原因。
+// 每次选择以 ResRob 广播，携带玩家 ID、是否叫或抢、当前的倍数和下一个轮到的玩家 ID，叫地主结束时为 0。
+func (table *Table) rob(c *Client, rob bool) *requestError {
+	game := table.GameManage
+	if table.Bidding != BidRob {
+		return &requestError{common.ErrCodeWrongState, "这张牌桌是叫分玩法"}
+	}
+	if table.State != GameCallScore {
+		return &requestError{common.ErrCodeWrongState, "现在不是叫地主阶段"}
+	}
+	if table.turnClient() != c || c.IsCalled && !game.RobFinal {
+		logs.Debug("user [%v] rob turn err", c.UserInfo.Username)
+		return &requestError{common.ErrCodeNotYourTurn, "还没有轮到你叫地主"}
+	}
+	game.CallScores[c.UserInfo.UserId] = 0
+	if rob {
+		game.CallScores[c.UserInfo.UserId] = 1
+		if game.Caller == nil {
+			game.Caller = c
+			game.MaxCallScore = 1
+		} else {
+			game.Robs++
+			game.Multiple *= 2
+		}
+		game.MaxCallScoreTurn = c
+	}
+	c.IsCalled = true
+	var next *Client
+	switch {
+	case game.RobFinal:
+	case !table.allCalled():
+		next = table.next(c)
+	case game.Caller != nil && game.MaxCallScoreTurn != game.Caller:
+		game.RobFinal = true
+		next = game.Caller
+	}
+	var nextId UserId
+	if next != nil {
+		game.Turn = next
+		nextId = next.UserInfo.UserId
+	}
+	table.broadcast([]interface{}{common.ResRob, c.UserInfo.UserId, rob, game.Multiple, nextId})
+	if next == nil {
+		logs.Debug("rob end")
+		table.callEnd()
+	} else {
+		table.startTurn()
+	}
+	return nil
+}
+
+// wantRob 由机器人策略决定是否叫地主或抢地主：策略会叫分时叫地主，会叫 2 分以上时才抢地主。调用方需持有牌桌的锁。
+func (c *Client) wantRob() bool {
+	view := c.gameView()
+	view.MaxCallScore = 0
+	score := c.strategy.Bid(view)
+	if c.Table.GameManage.Caller == nil {
+		return score > 0
+	}
+	return score >= 2
+}
@@ -6,13 +6,37 @@ import (
 	"time"
 )
 
+// RobotLevel 表示房间中机器人的难度。
+type RobotLevel int
+
+const (
+
+	// RobotEasy 是简单难度，使用 EasyStrategy。
+	RobotEasy RobotLevel = iota
+
+	// RobotNormal 是普通难度，使用 NormalStrategy。
+	RobotNormal
+
+	// RobotHard 是困难难度，使用 HardStrategy。
+	RobotHard
+
+	// RobotExpert 是专家难度，使用 ExpertStrategy。
+	RobotExpert
+)
+
+// robotInboxSize 是机器人消息通道的容量。
+// 牌桌持有锁时向机器人发送消息，而机器人处理消息时需要获取牌桌的锁，通道写满会导致死锁，
+// 因此容量要足够容纳机器人思考期间牌桌上发出的所有消息（出牌、不出、托管、聊天等）。
+const robotInboxSize = 64
+
 // runRobot 运行玩游戏的机器人逻辑。
 // 它监听两个通道：`c.toServer` 和 `c.toRobot`。
 // 如果 `c.toServer` 有消息，它会使用 `wsRequest` 将消息发送到服务器。
 // 如果 `c.toRobot` 有消息，它会根据协议代码处理消息。
 // - 如果代码是 `common.ResDealPoker`，机器人将调用 autoCallScore 函数。
 // - 如果代码是 `common.ResCallScore`，机器人会检查是否需要调用分数，并在必要时调用 autoCallScore 函数。
-// - 如果代码是 `common.ResShotPoker`，机器人将调用 autoShotPoker 函数。
+// - 如果代码是 `common.ResRob`，轮到机器人叫地主或抢地主时调用 autoCallScore 函数。
+// - 如果代码是 `common.ResShotPoker` 或 `common.ResPass`，轮到机器人时将调用 autoShotPoker 函数。
 // - 如果代码是 `common.ResShowPoker`，如果轮到或者没有人轮到并且机器人是地主，机器人将调用 autoShotPoker 函数。
 // - 如果代码是 `common.ResGameOver`，机器人将 `c.Ready` 设置为 true。
 // 该函数无限循环运行，直到“c.toServer”或“c.toRobot”通道关闭。
@@ -63,22 +87,34 @@ func (c *Client) runRobot() {
 					}
 					c.Table.Lock.RUnlock()
 
-				case common.ResShotPoker:
+				case common.ResRob:
 					time.Sleep(time.Second)
 					c.Table.Lock.RLock()
-					if c.Table.GameManage.Turn == c {
-						c.autoShotPoker()
+					if c.Table.State == GameCallScore && c.Table.turnClient() == c {
+						c.autoCallScore()
 					}
 					c.Table.Lock.RUnlock()
 
+				case common.ResShotPoker, common.ResPass:
+					time.Sleep(time.Second)
+					c.Table.Lock.RLock()
+					turn := c.Table.GameManage.Turn == c
+					view := c.gameView()
+					c.Table.Lock.RUnlock()
+					if turn {
+						c.autoShotPoker(view)
+					}
+
 				case common.ResShowPoker:
 					time.Sleep(time.Second)
 					//logs.Debug("robot [%v] role [%v] receive message ResShowPoker turn :%v", c.UserInfo.Username, c.UserInfo.Role, c.Table.GameManage.Turn.UserInfo.Username)
 					c.Table.Lock.RLock()
-					if c.Table.GameManage.Turn == c || (c.Table.GameManage.Turn == nil && c.UserInfo.Role == RoleLandlord) {
-						c.autoShotPoker()
-					}
+					turn := c.Table.GameManage.Turn == c || (c.Table.GameManage.Turn == nil && c.UserInfo.Role == RoleLandlord)
+					view := c.gameView()
 					c.Table.Lock.RUnlock()
+					if turn {
+						c.autoShotPoker(view)
+					}
 				case common.ResGameOver:
 					c.Ready = true
 				}
@@ -88,13 +124,10 @@ func (c *Client) runRobot() {
 }
 
 // autoShotPoker 自动出牌
-// 该方法判断机器人是否需要出牌，并根据规则选择要出的牌。
-// 如果机器人的出牌队列为空或者上一次出牌的玩家是机器人自己，
-// 机器人将选择手中的第一张牌进行出牌；否则，机器人将选择
-// 手中大于上一次出牌的牌进行出牌。
-// 出牌时，将牌转换为 float64 类型的数值，并将它们作为参数请求服务器。
+// 该方法把当前牌局的只读视图 view 交给机器人策略，由策略选择要出的牌，并将出牌请求发送给服务器。
+// 策略可能要搜索一段时间，调用时不持有牌桌的锁；出牌时如果已经不再轮到机器人，请求会被拒绝。
 // 该方法可以捕获异常并在异常发生时进行日志记录。
-func (c *Client) autoShotPoker() {
+func (c *Client) autoShotPoker(view GameView) {
 	//因为机器人休眠一秒后才出牌，有可能因用户退出而关闭chan
 	defer func() {
 		err := recover()
@@ -103,11 +136,16 @@ func (c *Client) autoShotPoker() {
 		}
 	}()
 	logs.Debug("robot [%v] auto-shot poker", c.UserInfo.Username)
-	shotPokers := make([]int, 0)
-	if len(c.Table.GameManage.LastShotPoker) == 0 || c.Table.GameManage.LastShotClient == c {
-		shotPokers = append(shotPokers, c.HandPokers[0])
-	} else {
-		shotPokers = common.CardsAbove(c.HandPokers, c.Table.GameManage.LastShotPoker)
+	c.toServer <- c.shotPokerRequest(view)
+}
+
+// shotPokerRequest 由机器人策略选择要出的牌，返回对应的 ReqShotPoker 请求，不出时返回 ReqPass 请求。
+// 请求的格式与网页客户端发送的一致，牌的编号转换为 float64 类型的数值。
+func (c *Client) shotPokerRequest(view GameView) []interface{} {
+	shotPokers := c.strategy.Play(view)
+	if len(shotPokers) == 0 {
+		logs.Debug("robot [%v] autoShotPoker pass", c.UserInfo.Username)
+		return []interface{}{float64(common.ReqPass)}
 	}
 	float64Pokers := make([]interface{}, 0)
 	for _, poker := range shotPokers {
@@ -116,10 +154,11 @@ func (c *Client) autoShotPoker() {
 	req := []interface{}{float64(common.ReqShotPoker)}
 	req = append(req, float64Pokers)
 	logs.Debug("robot [%v] autoShotPoker %v", c.UserInfo.Username, float64Pokers)
-	c.toServer <- req
+	return req
 }
 
 // 自动叫分
+// 由机器人策略根据牌局视图决定叫几分。
 func (c *Client) autoCallScore() {
 	defer func() {
 		err := recover()
@@ -127,6 +166,45 @@ func (c *Client) autoCallScore() {
 			logs.Warn("autoCallScore err : %v", err)
 		}
 	}()
-	logs.Debug("robot [%v] autoCallScore", c.UserInfo.Username)
-	c.toServer <- []interface{}{float64(common.ReqCallScore), float64(3)}
+	c.toServer <- c.callScoreRequest()
+}
+
+// callScoreRequest 由机器人策略决定叫几分，返回对应的 ReqCallScore 请求；抢地主的牌桌返回 ReqRob 请求，见 wantRob。
+// 调用方需持有牌桌的锁。
+func (c *Client) callScoreRequest() []interface{} {
+	if c.Table.Bidding == BidRob {
+		rob := c.wantRob()
+		logs.Debug("robot [%v] autoRob %t", c.UserInfo.Username, rob)
+		return []interface{}{float64(common.ReqRob), rob}
+	}
+	score := c.strategy.Bid(c.gameView())
+	logs.Debug("robot [%v] autoCallScore %d", c.UserInfo.Username, score)
+	return []interface{}{float64(common.ReqCallScore), float64(score)}
+}
+
+// gameView 生成当前牌局的只读视图，所有切片都是副本。调用方需持有牌桌的锁。
+func (c *Client) gameView() GameView {
+	game := c.Table.GameManage
+	view := GameView{
+		Rules:         c.Table.Rules,
+		HandPokers:    append([]int(nil), c.HandPokers...),
+		MaxCallScore:  game.MaxCallScore,
+		LastShotIndex: -1,
+		ShotPokers:    append([]int(nil), game.ShotPokers...),
+	}
+	if c.Table.State == GamePlaying {
+		view.BottomPokers = append([]int(nil), game.Pokers...)
+	}
+	for i, player := range c.Table.players(c) {
+		view.Players = append(view.Players, PlayerView{
+			UserId: player.UserInfo.UserId,
+			Role:   player.UserInfo.Role,
+			Cards:  len(player.HandPokers),
+		})
+		if player == game.LastShotClient && player != c {
+			view.LastShotIndex = i
+			view.LastShot = append([]int(nil), game.LastShotPoker...)
+		}
+	}
+	return view
 }
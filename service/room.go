@@ -2,13 +2,24 @@ package service
 
 import (
 	"github.com/astaxie/beego/logs"
+	"landlord/common"
 	"sync"
+	"time"
 )
 
 // roomManager 是 RoomManager 的一个实例，用于管理多个房间及其桌子。
-// 它初始化为两个房间，每个房间都有一个唯一的 RoomId。每个房间都有自己的一套桌子。
+// 它初始化为五个房间，每个房间都有一个唯一的 RoomId。每个房间都有自己的一套桌子。
+// 房间 1、2 使用经典玩法，房间 3 不允许四带二，房间 5 是与专家机器人对战的经典玩法房间，房间 6 是抢地主的经典玩法房间。
+// 前端目前只支持三人牌桌和三张底牌，四人两副牌玩法（common.TwoDeckRules）的房间 4 暂不开放。
 // `AllowRobot` 指定是否允许机器人进入房间。
 // `EntranceFee` 指定玩家进入房间需要支付的费用。
+// `MinCoin`、`MaxCoin` 指定进入房间的金币范围：房间 1 面向新手，金币太多的玩家不能进入；
+// 房间 2 和专家机器人房间 5 面向高手，金币不足的玩家不能进入。
+// `Rules` 指定房间使用的玩法，决定发牌张数、允许的牌型和牌型大小。
+// `RobotLevel` 指定房间中机器人的难度，也用于托管。
+// `TurnTimeout` 指定每次叫分和出牌的时限，`AutoPlayAfter` 指定连续超时几次后进入托管。
+// `Bidding` 指定叫地主的方式，房间 6 使用抢地主，其他房间叫分。
+// `AllPass` 指定所有人都不叫时的处理，所有房间都重新发牌，`MaxRedeals` 指定最多连续重新发牌几次。
 // `Tables` 是 TableId 到 Table 实例的映射，代表房间中的桌子。
 // 每次创建新表时，`TableId` 都会递增。
 // 应使用适当的锁定来访问 roomManager 实例，以确保线程安全。
@@ -16,21 +27,105 @@ var (
 	roomManager = RoomManager{
 		Rooms: map[int]*Room{
 			1: {
-				RoomId:      1,
-				AllowRobot:  true,
-				EntranceFee: 200,
-				Tables:      make(map[TableId]*Table),
+				RoomId:        1,
+				AllowRobot:    true,
+				RobotLevel:    RobotNormal,
+				EntranceFee:   200,
+				MaxCoin:       50000,
+				TurnTimeout:   defaultTurnTimeout,
+				AutoPlayAfter: defaultAutoPlayAfter,
+				MaxRedeals:    defaultMaxRedeals,
+				Rules:         common.ClassicRules{},
+				Tables:        make(map[TableId]*Table),
 			},
 			2: {
-				RoomId:      2,
-				AllowRobot:  false,
-				EntranceFee: 200,
-				Tables:      make(map[TableId]*Table),
+				RoomId:        2,
+				AllowRobot:    false,
+				EntranceFee:   200,
+				MinCoin:       5000,
+				TurnTimeout:   defaultTurnTimeout,
+				AutoPlayAfter: defaultAutoPlayAfter,
+				MaxRedeals:    defaultMaxRedeals,
+				Rules:         common.ClassicRules{},
+				Tables:        make(map[TableId]*Table),
+			},
+			3: {
+				RoomId:        3,
+				AllowRobot:    false,
+				EntranceFee:   200,
+				Rules:         common.NoKickerBombRules{},
+				TurnTimeout:   defaultTurnTimeout,
+				AutoPlayAfter: defaultAutoPlayAfter,
+				MaxRedeals:    defaultMaxRedeals,
+				Tables:        make(map[TableId]*Table),
+			},
+			5: {
+				RoomId:        5,
+				AllowRobot:    true,
+				RobotLevel:    RobotExpert,
+				EntranceFee:   200,
+				MinCoin:       2000,
+				TurnTimeout:   defaultTurnTimeout,
+				AutoPlayAfter: defaultAutoPlayAfter,
+				MaxRedeals:    defaultMaxRedeals,
+				Rules:         common.ClassicRules{},
+				Tables:        make(map[TableId]*Table),
+			},
+			6: {
+				RoomId:        6,
+				AllowRobot:    false,
+				EntranceFee:   200,
+				Bidding:       BidRob,
+				TurnTimeout:   defaultTurnTimeout,
+				AutoPlayAfter: defaultAutoPlayAfter,
+				MaxRedeals:    defaultMaxRedeals,
+				Rules:         common.ClassicRules{},
+				Tables:        make(map[TableId]*Table),
 			},
 		},
 	}
 )
 
+const (
+
+	// defaultTurnTimeout 是房间默认的叫分和出牌时限。
+	defaultTurnTimeout = 30 * time.Second
+
+	// defaultAutoPlayAfter 是房间默认的连续超时几次后进入托管。
+	defaultAutoPlayAfter = 2
+
+	// defaultMaxRedeals 是房间默认的所有人都不叫时最多连续重新发牌的次数。
+	defaultMaxRedeals = 3
+)
+
+// BiddingMode 是房间的叫地主方式。
+type BiddingMode int
+
+const (
+
+	// BidScore 是叫分：每个玩家依次叫 1、2、3 分或不叫，叫分最高的玩家当地主，叫分就是底分。
+	BidScore BiddingMode = iota
+
+	// BidRob 是抢地主：有人叫地主之后，其他玩家依次可以抢地主，每抢一次倍数翻倍，
+	// 有人抢过时叫地主的玩家最后还可以再抢一次，最后一个叫到或抢到的玩家当地主，底分为 1 分，见 Table.rob。
+	BidRob
+)
+
+// AllPassPolicy 是所有人都不叫时的处理方式。
+type AllPassPolicy int
+
+const (
+
+	// AllPassRedeal 表示重新发牌，由下一个玩家第一个叫分，连续重新发牌达到 Room.MaxRedeals 次后按 AllPassFirstBidder 处理。
+	AllPassRedeal AllPassPolicy = iota
+
+	// AllPassFirstBidder 表示强制本局第一个叫分的玩家当地主，叫分按 1 分计算。
+	AllPassFirstBidder
+
+	// AllPassCreator 表示强制牌桌的创建者当地主，叫分按 1 分计算。
+	AllPassCreator
+)
+
 // RoomId 代表房间的唯一标识符。它的类型是“int”。
 type RoomId int
 
@@ -49,12 +144,59 @@ type RoomManager struct {
 // - AllowRobot: 是否允许加入机器人，类型为 bool。
 // - Tables: 存储该房间中的牌桌，类型为 map[TableId]*Table。
 // - EntranceFee: 加入房间需要支付的入场费，类型为 int。
+// - MinCoin: 进入房间需要的最少金币，不足入场费时按入场费计算，类型为 int。
+// - MaxCoin: 进入房间允许的最多金币，为 0 时不限制，类型为 int。
+// - Rules: 房间使用的玩法，类型为 common.RuleSet。
+// - RobotLevel: 房间中机器人的难度，类型为 RobotLevel。
+// - TurnTimeout: 每次叫分和出牌的时限，为 0 时不限时，类型为 time.Duration。
+// - AutoPlayAfter: 连续超时几次后进入托管，为 0 时不托管，类型为 int。
+// - Bidding: 叫地主的方式，默认叫分，类型为 BiddingMode。
+// - AllPass: 所有人都不叫时的处理方式，默认重新发牌，类型为 AllPassPolicy。
+// - MaxRedeals: 所有人都不叫时最多连续重新发牌的次数，类型为 int。
 type Room struct {
-	RoomId      RoomId
-	Lock        sync.RWMutex
-	AllowRobot  bool
-	Tables      map[TableId]*Table
-	EntranceFee int
+	RoomId        RoomId
+	Lock          sync.RWMutex
+	AllowRobot    bool
+	RobotLevel    RobotLevel
+	Tables        map[TableId]*Table
+	EntranceFee   int
+	MinCoin       int
+	MaxCoin       int
+	Rules         common.RuleSet
+	TurnTimeout   time.Duration
+	AutoPlayAfter int
+	Bidding       BiddingMode
+	AllPass       AllPassPolicy
+	MaxRedeals    int
+}
+
+// minCoin 返回进入房间需要的最少金币，至少要够支付一次入场费。
+func (r *Room) minCoin() int {
+	if r.MinCoin < r.EntranceFee {
+		return r.EntranceFee
+	}
+	return r.MinCoin
+}
+
+// allowCoin 检查金币余额 coin 是否在房间允许的范围内。
+func (r *Room) allowCoin(coin int) bool {
+	return coin >= r.minCoin() && (r.MaxCoin == 0 || coin <= r.MaxCoin)
+}
+
+// tables 列出房间中所有的牌桌，每张牌桌为牌桌 ID、入座的人数和是否在叫分或出牌阶段（1 或 0），
+// 用于 ResJoinRoom 和 ResTableList。坐满的牌桌也会列出，可以观战。读取每张牌桌时对牌桌加读锁。
+func (r *Room) tables() [][3]int {
+	res := make([][3]int, 0, len(r.Tables))
+	for _, table := range r.Tables {
+		table.Lock.RLock()
+		inGame := 0
+		if table.State.inGame() {
+			inGame = 1
+		}
+		res = append(res, [3]int{int(table.TableId), len(table.TableClients), inGame})
+		table.Lock.RUnlock()
+	}
+	return res
 }
 
 // newTable 在房间中创建一张新桌子。
@@ -68,12 +210,16 @@ func (r *Room) newTable(client *Client) (table *Table) {
 	table = &Table{
 		TableId:      roomManager.TableIdInc,
 		Creator:      client,
-		TableClients: make(map[UserId]*Client, 3),
+		Rules:        r.Rules,
+		Bidding:      r.Bidding,
+		Seats:        make([]*Client, r.Rules.Players()),
+		TableClients: make(map[UserId]*Client, r.Rules.Players()),
+		Watchers:     make(map[UserId]*Client),
 		GameManage: &GameManage{
 			FirstCallScore: client,
 			Multiple:       1,
 			LastShotPoker:  make([]int, 0),
-			Pokers:         make([]int, 0, 54),
+			Pokers:         make([]int, 0, r.Rules.DeckSize()),
 		},
 	}
 	r.Tables[table.TableId] = table
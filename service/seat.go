@@ -0,0 +1,62 @@
+package service
+
+// emptySeat 是 ResJoinTable 中空座位的玩家 ID。
+const emptySeat UserId = -1
+
+// sit 让玩家 c 坐到编号最小的空座位上，没有空座位时返回 false。调用方需持有牌桌的锁。
+func (table *Table) sit(c *Client) bool {
+	for i, client := range table.Seats {
+		if client == nil {
+			c.Seat = i
+			table.Seats[i] = c
+			table.TableClients[c.UserInfo.UserId] = c
+			return true
+		}
+	}
+	return false
+}
+
+// unsit 让玩家 c 离开座位。
+func (table *Table) unsit(c *Client) {
+	if c.Seat < len(table.Seats) && table.Seats[c.Seat] == c {
+		table.Seats[c.Seat] = nil
+	}
+	delete(table.TableClients, c.UserInfo.UserId)
+}
+
+// full 判断牌桌的座位是否已经坐满。
+func (table *Table) full() bool {
+	for _, client := range table.Seats {
+		if client == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// next 返回按顺时针方向坐在玩家 c 之后的下一个玩家，跳过空座位，只有 c 一个人时返回 c。
+func (table *Table) next(c *Client) *Client {
+	n := len(table.Seats)
+	for i := 1; i <= n; i++ {
+		if client := table.Seats[(c.Seat+i)%n]; client != nil {
+			return client
+		}
+	}
+	return c
+}
+
+// players 返回从玩家 from 开始按顺时针方向排列的所有玩家，from 为 nil 时从 0 号座位开始。
+func (table *Table) players(from *Client) []*Client {
+	start := 0
+	if from != nil {
+		start = from.Seat
+	}
+	n := len(table.Seats)
+	players := make([]*Client, 0, n)
+	for i := 0; i < n; i++ {
+		if client := table.Seats[(start+i)%n]; client != nil {
+			players = append(players, client)
+		}
+	}
+	return players
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"landlord/common"
+)
+
+// simulationInboxSize 是模拟对局中每个玩家消息通道的容量，每处理一个请求后都会清空通道。
+const simulationInboxSize = 64
+
+// simulationMaxSteps 是模拟对局中一局最多处理的请求数，超过时认为牌局卡住了。
+const simulationMaxSteps = 10000
+
+// Simulation 在没有 websocket 连接的情况下让若干机器人策略在一张牌桌上反复对局，用于评估机器人和调整平衡性。
+// 请求经由 wsRequest 处理，发牌、叫分、出牌和 gameOver 结算与线上牌桌走相同的流程，
+// 但不经过机器人的 toServer 通道，也没有出牌前的等待。
+//
+// - Rules: 牌桌使用的玩法。
+// - Strategies: 每个座位的机器人策略，按出牌顺序排列，个数必须等于 Rules.Players()。
+// - Seed: 不为 0 时第 i 局（从 0 开始）用种子 Seed+i 洗牌，发牌可以复现；为 0 时每局随机洗牌。
+type Simulation struct {
+	Rules      common.RuleSet
+	Strategies []RobotStrategy
+	Seed       int64
+}
+
+// SeatStats 是模拟对局中一个座位的胜负统计。
+type SeatStats struct {
+	LandlordGames int
+	LandlordWins  int
+	FarmerGames   int
+	FarmerWins    int
+}
+
+// SimulationStats 是模拟对局的统计结果。
+//
+// - Games: 对局数。
+// - Seats: 每个座位的胜负，与 Simulation.Strategies 一一对应。
+// - LandlordWins: 地主获胜的局数。
+// - CallScore: 所有对局地主叫分的总和。
+// - Multiple: 所有对局结束时倍数（GameManage.Multiple）的总和。
+// - Bombs: 打出的炸弹和王炸的总数。
+// - BombGames: 打出过炸弹或王炸的局数。
+// - Shots: 所有对局出牌的总次数，不出也算一次。
+type SimulationStats struct {
+	Games        int
+	Seats        []SeatStats
+	LandlordWins int
+	CallScore    int
+	Multiple     int
+	Bombs        int
+	BombGames    int
+	Shots        int
+}
+
+// Run 连续模拟 games 局，返回统计结果。每局结束后所有玩家请求重新开始，叫分从下一个玩家开始轮转。
+func (s *Simulation) Run(games int) (stats SimulationStats, err error) {
+	players := s.Rules.Players()
+	if len(s.Strategies) != players {
+		err = fmt.Errorf("rule set %s needs %d strategies, got %d", s.Rules.Name(), players, len(s.Strategies))
+		return
+	}
+	room := &Room{
+		Rules:  s.Rules,
+		Tables: make(map[TableId]*Table),
+	}
+	clients := make([]*Client, players)
+	for i, strategy := range s.Strategies {
+		clients[i] = &Client{
+			Room:       room,
+			HandPokers: make([]int, 0, 21),
+			UserInfo: &UserInfo{
+				UserId:   UserId(i + 1),
+				Username: fmt.Sprintf("SIM-%d", i),
+				Coin:     10000,
+			},
+			IsRobot:  true,
+			toRobot:  make(chan []interface{}, simulationInboxSize),
+			strategy: strategy,
+		}
+	}
+	table := room.newTable(clients[0])
+	if s.Seed != 0 {
+		table.SetNextSeed(s.Seed)
+	}
+	for _, c := range clients {
+		table.joinTable(c)
+	}
+
+	stats.Seats = make([]SeatStats, players)
+	bombs, steps := 0, 0
+	for {
+		// 只统计第一个座位收到的消息，其他座位收到的是相同的广播
+		for _, msg := range drainInbox(clients) {
+			switch msg[0] {
+			case common.ResPass:
+				stats.Shots++
+			case common.ResShotPoker:
+				stats.Shots++
+				if hand, err := s.Rules.Classify(msg[2].([]int)); err == nil && hand.IsBomb() {
+					bombs++
+				}
+			case common.ResGameOver:
+				winner := clients[int(msg[1].(UserId))-1]
+				stats.record(clients, winner, table.GameManage, bombs)
+				bombs, steps = 0, 0
+				if s.Seed != 0 {
+					table.SetNextSeed(s.Seed + int64(stats.Games))
+				}
+			}
+		}
+		if stats.Games >= games {
+			return
+		}
+		if steps++; steps > simulationMaxSteps {
+			err = errors.New("simulated game does not end")
+			return
+		}
+
+		switch table.State {
+		case GameCallScore:
+			c := table.GameManage.Turn
+			if c == nil {
+				c = table.GameManage.FirstCallScore
+			}
+			wsRequest(c.callScoreRequest(), c)
+		case GamePlaying:
+			c := table.GameManage.Turn
+			wsRequest(c.shotPokerRequest(c.gameView()), c)
+		case GameEnd:
+			for _, c := range clients {
+				wsRequest([]interface{}{float64(common.ReqRestart)}, c)
+			}
+		default:
+			err = fmt.Errorf("unexpected table state %d", table.State)
+			return
+		}
+	}
+}
+
+// record 记录一局的结果，winner 是最先出完牌的玩家，bombs 是这一局打出的炸弹数。
+func (stats *SimulationStats) record(clients []*Client, winner *Client, game *GameManage, bombs int) {
+	stats.Games++
+	stats.CallScore += game.MaxCallScore
+	stats.Multiple += game.Multiple
+	stats.Bombs += bombs
+	if bombs > 0 {
+		stats.BombGames++
+	}
+	if winner.UserInfo.Role == RoleLandlord {
+		stats.LandlordWins++
+	}
+	for i, c := range clients {
+		won := c.UserInfo.Role == winner.UserInfo.Role
+		seat := &stats.Seats[i]
+		if c.UserInfo.Role == RoleLandlord {
+			seat.LandlordGames++
+			if won {
+				seat.LandlordWins++
+			}
+		} else {
+			seat.FarmerGames++
+			if won {
+				seat.FarmerWins++
+			}
+		}
+	}
+}
+
+// drainInbox 清空所有玩家的消息通道，返回第一个玩家收到的消息。
+func drainInbox(clients []*Client) (msgs [][]interface{}) {
+	for i, c := range clients {
+		for len(c.toRobot) > 0 {
+			msg := <-c.toRobot
+			if i == 0 {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"github.com/astaxie/beego/logs"
+	"github.com/gorilla/websocket"
+	"landlord/common"
+	"time"
+)
+
+// Snapshot 是牌局在某一时刻的全貌，玩家断线重连时以 ResSnapshot 发送，开始观战时随 ResWatchTable 发送，客户端据此恢复界面。
+//
+// - TableId: 牌桌 ID。
+// - State: 牌桌状态。
+// - Bidding: 牌桌的叫地主方式。
+// - Players: 按座位号排列的玩家，与 ResJoinTable 中的顺序一致。
+// - HandPokers: 接收快照的玩家的手牌，观战者为空。
+// - MaxCallScore: 当前最高的叫分，抢地主玩法有人叫地主后为 1。
+// - Landlord: 地主的玩家 ID，叫分阶段为 0。
+// - BottomPokers: 底牌，叫分阶段为空。
+// - LastShotUser、LastShotPokers: 需要压过的牌及其玩家 ID，自由出牌时为 0 和空。
+// - Turn: 轮到的玩家 ID。
+// - Deadline: 本回合的截止时间（Unix 毫秒），不计时为 0。
+// - Multiple: 当前的倍数。
+type Snapshot struct {
+	TableId        TableId          `json:"table_id"`
+	State          TableState       `json:"state"`
+	Bidding        BiddingMode      `json:"bidding"`
+	Players        []SnapshotPlayer `json:"players"`
+	HandPokers     []int            `json:"hand_pokers"`
+	MaxCallScore   int              `json:"max_call_score"`
+	Landlord       UserId           `json:"landlord"`
+	BottomPokers   []int            `json:"bottom_pokers"`
+	LastShotUser   UserId           `json:"last_shot_user"`
+	LastShotPokers []int            `json:"last_shot_pokers"`
+	Turn           UserId           `json:"turn"`
+	Deadline       int64            `json:"deadline"`
+	Multiple       int              `json:"multiple"`
+}
+
+// SnapshotPlayer 是快照中的一个玩家，Cards 是剩余的手牌张数，CallScore 是叫的分，还没有叫分时为 -1。
+type SnapshotPlayer struct {
+	UserId    UserId `json:"user_id"`
+	Username  string `json:"username"`
+	Role      int    `json:"role"`
+	Cards     int    `json:"cards"`
+	CallScore int    `json:"call_score"`
+	AutoPlay  bool   `json:"auto_play"`
+}
+
+// snapshot 生成玩家 c 视角的牌局快照，其他玩家的手牌只给出张数；c 为 nil 时是观战者的视角，不含手牌。
+// 调用方需持有牌桌的锁。
+func (table *Table) snapshot(c *Client) Snapshot {
+	game := table.GameManage
+	snapshot := Snapshot{
+		TableId:        table.TableId,
+		State:          table.State,
+		Bidding:        table.Bidding,
+		HandPokers:     []int{},
+		Players:        []SnapshotPlayer{},
+		MaxCallScore:   game.MaxCallScore,
+		BottomPokers:   []int{},
+		LastShotPokers: []int{},
+		Multiple:       game.Multiple,
+	}
+	if c != nil {
+		snapshot.HandPokers = append(snapshot.HandPokers, c.HandPokers...)
+	}
+	for _, player := range table.players(nil) {
+		callScore, ok := game.CallScores[player.UserInfo.UserId]
+		if !ok {
+			callScore = -1
+		}
+		snapshot.Players = append(snapshot.Players, SnapshotPlayer{
+			UserId:    player.UserInfo.UserId,
+			Username:  player.UserInfo.Username,
+			Role:      player.UserInfo.Role,
+			Cards:     len(player.HandPokers),
+			CallScore: callScore,
+			AutoPlay:  player.autoPlay,
+		})
+	}
+	if table.State == GamePlaying {
+		snapshot.Landlord = game.MaxCallScoreTurn.UserInfo.UserId
+		snapshot.BottomPokers = append(snapshot.BottomPokers, game.Pokers...)
+	}
+	if game.LastShotClient != nil {
+		snapshot.LastShotUser = game.LastShotClient.UserInfo.UserId
+		snapshot.LastShotPokers = append(snapshot.LastShotPokers, game.LastShotPoker...)
+	}
+	if turn := table.turnClient(); turn != nil {
+		snapshot.Turn = turn.UserInfo.UserId
+	}
+	if !table.turnDeadline.IsZero() {
+		snapshot.Deadline = table.turnDeadline.UnixNano() / int64(time.Millisecond)
+	}
+	return snapshot
+}
+
+// findSeat 查找玩家 userId 在进行中的牌局里的座位，没有时返回 nil。
+func findSeat(userId UserId) *Client {
+	roomManager.Lock.RLock()
+	defer roomManager.Lock.RUnlock()
+	for _, room := range roomManager.Rooms {
+		room.Lock.RLock()
+		for _, table := range room.Tables {
+			table.Lock.RLock()
+			c, ok := table.TableClients[userId]
+			playing := table.State.inGame()
+			table.Lock.RUnlock()
+			if ok && playing && !c.IsRobot {
+				room.Lock.RUnlock()
+				return c
+			}
+		}
+		room.Lock.RUnlock()
+	}
+	return nil
+}
+
+// resume 在玩家 userId 重连时，将新的连接 conn 接到其在进行中的牌局里的座位上，
+// 取消机器人托管，并以 ResSnapshot 发送牌局快照，返回座位上的客户端。没有进行中的牌局时返回 nil。
+// 旧的连接如果还没有断开会被关闭。
+func resume(userId UserId, conn *websocket.Conn) *Client {
+	c := findSeat(userId)
+	if c == nil {
+		return nil
+	}
+	table := c.Table
+	table.Lock.Lock()
+	defer table.Lock.Unlock()
+	if !table.State.inGame() {
+		return nil
+	}
+	logs.Debug("user [%d] resume table [%d]", userId, table.TableId)
+	old := c.conn
+	c.conn = conn
+	c.offline = false
+	if old != nil {
+		old.Close()
+	}
+	c.sendMsg([]interface{}{common.ResSnapshot, table.snapshot(c)})
+	table.setAutoPlay(c, false)
+	if table.turnClient() == c {
+		table.startTurn()
+	}
+	return c
+}
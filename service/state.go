@@ -0,0 +1,79 @@
+package service
+
+import (
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+)
+
+// TableState 是牌桌的状态，只能按 stateTransitions 转换，见 Table.setState。
+type TableState int
+
+// stateTransitions 列出每个状态可以转换到的状态。
+// 坐满后开始叫分，叫分结束后出牌，所有人都不叫时可能重新发牌、重新叫分，有人出完手牌后结束；
+// 结束后重新开始时先回到等待状态，坐满再开始叫分，见 Table.reset。
+var stateTransitions = map[TableState][]TableState{
+	GameWaitting:  {GameCallScore},
+	GameCallScore: {GamePlaying, GameCallScore},
+	GamePlaying:   {GameEnd},
+	GameEnd:       {GameWaitting},
+}
+
+// stateRequests 列出每个状态下牌桌上的玩家可以发出的请求，其他请求以 ErrCodeWrongState 拒绝，见 Table.allows。
+var stateRequests = map[TableState][]int{
+	GameWaitting:  {common.ReqChat},
+	GameCallScore: {common.ReqCallScore, common.ReqRob, common.ReqAutoPlay, common.ReqChat},
+	GamePlaying:   {common.ReqShotPoker, common.ReqPass, common.ReqHint, common.ReqAutoPlay, common.ReqChat},
+	GameEnd:       {common.ReqDealPoker, common.ReqRestart, common.ReqChat},
+}
+
+// StateListener 在牌桌的状态从 from 转换到 to 之后被调用，调用时持有牌桌的锁。
+type StateListener func(table *Table, from, to TableState)
+
+// stateListeners 是订阅了牌桌状态变化的监听者，按订阅的顺序调用。
+var stateListeners []StateListener
+
+// SubscribeState 订阅所有牌桌的状态变化，例如计时、对局记录和统计。
+// 监听者在状态转换的 goroutine 中同步调用，不能再对牌桌加锁。SubscribeState 不是并发安全的，应在启动时调用。
+func SubscribeState(listener StateListener) {
+	stateListeners = append(stateListeners, listener)
+}
+
+// inGame 判断是否处于进行中的牌局，即叫分或出牌阶段。
+func (state TableState) inGame() bool {
+	return state == GameCallScore || state == GamePlaying
+}
+
+// setState 将牌桌的状态转换为 to 并通知所有监听者，调用方需持有牌桌的锁。
+// stateTransitions 不允许的转换会被拒绝并记录错误，返回 false。
+func (table *Table) setState(to TableState) bool {
+	from := table.State
+	allowed := false
+	for _, state := range stateTransitions[from] {
+		if state == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		logs.Error("table[%d] illegal state transition %d -> %d", table.TableId, from, to)
+		return false
+	}
+	table.State = to
+	logs.Debug("table[%d] state %d -> %d", table.TableId, from, to)
+	for _, listener := range stateListeners {
+		listener(table, from, to)
+	}
+	return true
+}
+
+// allows 判断牌桌当前的状态是否允许请求 req。
+func (table *Table) allows(req int) bool {
+	table.Lock.RLock()
+	defer table.Lock.RUnlock()
+	for _, r := range stateRequests[table.State] {
+		if r == req {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"landlord/common"
+	"math/rand"
+)
+
+// RobotStrategy 是机器人的决策逻辑，根据只读的牌局视图决定叫分和出牌。
+// Bid 返回要叫的分，0 表示不叫；Play 返回要出的牌，空切片表示不出。
+type RobotStrategy interface {
+	Bid(view GameView) int
+	Play(view GameView) []int
+}
+
+// PlayerView 是牌局视图中其他玩家的公开信息。
+type PlayerView struct {
+	UserId UserId
+	Role   int
+	Cards  int // 剩余张数
+}
+
+// GameView 是机器人决策时看到的牌局信息，是牌桌状态的只读副本，修改它不会影响牌桌。
+//
+// - Rules: 牌桌使用的玩法。
+// - HandPokers: 自己的手牌。
+// - Players: 从自己开始按出牌顺序排列的所有玩家，Players[0] 是自己。
+// - MaxCallScore: 当前最高叫分。
+// - LastShot: 需要压过的牌，自由出牌时为空。
+// - LastShotIndex: 打出 LastShot 的玩家在 Players 中的下标，自由出牌时为 -1。
+// - ShotPokers: 本局已经打出的所有牌。
+// - BottomPokers: 底牌，叫分结束后才公开。
+type GameView struct {
+	Rules         common.RuleSet
+	HandPokers    []int
+	Players       []PlayerView
+	MaxCallScore  int
+	LastShot      []int
+	LastShotIndex int
+	ShotPokers    []int
+	BottomPokers  []int
+}
+
+// isPartner 判断 Players 中下标为 i 的玩家是否为自己的农民队友。
+func (v GameView) isPartner(i int) bool {
+	return i > 0 && v.Players[0].Role == RoleFarmer && v.Players[i].Role == RoleFarmer
+}
+
+// enemyCards 返回对手中最少的剩余张数。
+func (v GameView) enemyCards() (cards int) {
+	for i, player := range v.Players {
+		if i > 0 && !v.isPartner(i) && (cards == 0 || player.Cards < cards) {
+			cards = player.Cards
+		}
+	}
+	return
+}
+
+// nextPartnerCards 下家是队友时返回队友的剩余张数，否则返回 0。
+func (v GameView) nextPartnerCards() int {
+	if len(v.Players) > 1 && v.isPartner(1) {
+		return v.Players[1].Cards
+	}
+	return 0
+}
+
+// partnerShot 判断需要压过的牌是否为队友所出。
+func (v GameView) partnerShot() bool {
+	return v.LastShotIndex > 0 && v.isPartner(v.LastShotIndex)
+}
+
+// unseenPokers 返回自己看不到的牌：整副牌去掉自己的手牌和已经打出的牌。
+func (v GameView) unseenPokers() []int {
+	known := make(map[int]bool, len(v.HandPokers)+len(v.ShotPokers))
+	for _, poker := range v.HandPokers {
+		known[poker] = true
+	}
+	for _, poker := range v.ShotPokers {
+		known[poker] = true
+	}
+	unseen := make([]int, 0, v.Rules.DeckSize()-len(known))
+	for poker := 0; poker < v.Rules.DeckSize(); poker++ {
+		if !known[poker] {
+			unseen = append(unseen, poker)
+		}
+	}
+	return unseen
+}
+
+// newRobotStrategy 返回难度 level 对应的机器人策略。
+func newRobotStrategy(level RobotLevel) RobotStrategy {
+	switch level {
+	case RobotNormal:
+		return NormalStrategy{}
+	case RobotHard:
+		return HardStrategy{}
+	case RobotExpert:
+		return ExpertStrategy{}
+	}
+	return EasyStrategy{}
+}
+
+// EasyStrategy 是简单机器人：总是叫 3 分，自由出牌时出手里编号最小的一张，
+// 跟牌时出能压过上家的最小的牌，压不过就不出。
+type EasyStrategy struct{}
+
+func (EasyStrategy) Bid(view GameView) int {
+	return 3
+}
+
+func (EasyStrategy) Play(view GameView) []int {
+	if len(view.LastShot) == 0 {
+		return []int{view.HandPokers[0]}
+	}
+	return common.LegalMovesWith(view.Rules, view.HandPokers, view.LastShot)[0]
+}
+
+// NormalStrategy 是普通机器人：按手牌强度叫分，按拆牌结果出牌，见 planCallScore 和 planShotPoker。
+type NormalStrategy struct{}
+
+func (NormalStrategy) Bid(view GameView) int {
+	return planCallScore(view.Rules, view.HandPokers, view.MaxCallScore, RobotNormal)
+}
+
+func (NormalStrategy) Play(view GameView) []int {
+	return planShotPoker(view)
+}
+
+// HardStrategy 是困难机器人：在普通机器人的基础上记牌。
+// 自由出牌时如果除了一手之外都是别人压不住的牌，就先出压不住的牌，把最后一手留到最后；
+// 跟牌时如果压过之后剩下的牌能这样走完，就不惜拆牌或用炸弹去压。
+type HardStrategy struct{}
+
+func (HardStrategy) Bid(view GameView) int {
+	return planCallScore(view.Rules, view.HandPokers, view.MaxCallScore, RobotHard)
+}
+
+func (HardStrategy) Play(view GameView) []int {
+	unseen := view.unseenPokers()
+	if len(view.LastShot) == 0 {
+		if controls := winningLine(view.Rules, view.HandPokers, unseen); len(controls) > 0 {
+			return controls[0]
+		}
+	} else if !view.partnerShot() {
+		for _, move := range common.LegalMovesWith(view.Rules, view.HandPokers, view.LastShot) {
+			rest := removePokers(view.HandPokers, move)
+			if len(move) > 0 && (len(rest) == 0 || len(winningLine(view.Rules, rest, unseen)) > 0) {
+				return move
+			}
+		}
+	}
+	return planShotPoker(view)
+}
+
+// winningLine 判断手牌 hand 拆牌后是否除了最多一手之外都是 unseen 中的牌压不住的，
+// 是则返回这些压不住的牌，否则返回 nil。
+func winningLine(rules common.RuleSet, hand, unseen []int) (controls [][]int) {
+	others := 0
+	for _, group := range common.Decompose(rules, hand) {
+		if len(common.LegalMovesWith(rules, unseen, group)) > 1 {
+			others++
+		} else {
+			controls = append(controls, group)
+		}
+	}
+	if others > 1 {
+		return nil
+	}
+	return
+}
+
+// planShotPoker 根据拆牌结果选择要出的牌，返回空切片表示不出。
+//
+// 能一手出完时直接出完。
+// 自由出牌时，如果只剩两手且其中一手是炸弹，先出炸弹夺回牌权；下家队友只剩一两张时送最小的单张或对子；
+// 否则出点数最小的一手牌，点数相同时出张数多的，尽量保留大牌和完整的组合；对手只剩一两张时避免出单张或对子。
+// 跟牌时不压队友的牌；优先出拆牌结果中现成的组合，其次是不增加手数的出法，
+// 对手快出完时才拆牌去压；炸弹留到残局，只在炸完能走完或对手快出完时使用。
+func planShotPoker(view GameView) []int {
+	rules := view.Rules
+	if hand, err := rules.Classify(view.HandPokers); err == nil {
+		if len(view.LastShot) == 0 {
+			return view.HandPokers
+		}
+		if last, err := rules.Classify(view.LastShot); err == nil && rules.Compare(last, hand) > 0 && !view.partnerShot() {
+			return view.HandPokers
+		}
+	}
+	groups := common.Decompose(rules, view.HandPokers)
+	if len(view.LastShot) == 0 {
+		return planLead(view, groups)
+	}
+	if view.partnerShot() {
+		return []int{}
+	}
+
+	isGroup := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		isGroup[common.SortStr(common.ToPokers(group))] = true
+	}
+	moves := common.LegalMovesWith(rules, view.HandPokers, view.LastShot)
+	var bombs [][]int
+	var breaking []int
+	for _, move := range moves {
+		if len(move) == 0 {
+			continue
+		}
+		hand, _ := rules.Classify(move)
+		if hand.IsBomb() {
+			bombs = append(bombs, move)
+			continue
+		}
+		if isGroup[common.SortStr(common.ToPokers(move))] {
+			return move
+		}
+		if breaking == nil && common.Turns(rules, removePokers(view.HandPokers, move)) < len(groups) {
+			breaking = move
+		}
+	}
+	if breaking != nil {
+		return breaking
+	}
+	enemyCards := view.enemyCards()
+	if enemyCards <= 4 {
+		for _, move := range moves {
+			if hand, _ := rules.Classify(move); len(move) > 0 && !hand.IsBomb() {
+				return move
+			}
+		}
+	}
+	for _, bomb := range bombs {
+		if enemyCards <= 2 || common.Turns(rules, removePokers(view.HandPokers, bomb)) <= 1 {
+			return bomb
+		}
+	}
+	return []int{}
+}
+
+// planLead 自由出牌时从拆好的组合 groups 中选出要出的一手。
+func planLead(view GameView, groups [][]int) []int {
+	rules := view.Rules
+	var normal, bombs [][]int
+	for _, group := range groups {
+		if hand, _ := rules.Classify(group); hand.IsBomb() {
+			bombs = append(bombs, group)
+		} else {
+			normal = append(normal, group)
+		}
+	}
+	if len(normal) == 0 || (len(groups) == 2 && len(bombs) > 0) {
+		return bombs[0]
+	}
+	// 下家队友快出完时，送一手最小的单张或对子让队友接
+	if partnerCards := view.nextPartnerCards(); partnerCards == 1 || partnerCards == 2 {
+		for _, move := range common.LegalMovesWith(rules, view.HandPokers, nil) {
+			if len(move) == partnerCards {
+				return move
+			}
+		}
+	}
+	// 对手只剩一张或两张时，尽量不出对手可能接得住的单张或对子
+	if enemyCards := view.enemyCards(); enemyCards <= 2 {
+		var avoid common.HandKind = common.KindSingle
+		if enemyCards == 2 {
+			avoid = common.KindPair
+		}
+		for _, group := range normal {
+			if hand, _ := rules.Classify(group); hand.Kind != avoid {
+				return group
+			}
+		}
+		return normal[len(normal)-1]
+	}
+	lead := normal[0]
+	lowest, _ := rules.Classify(lead)
+	for _, group := range normal[1:] {
+		if hand, _ := rules.Classify(group); hand.Rank == lowest.Rank && len(group) > len(lead) {
+			lead = group
+		}
+	}
+	return lead
+}
+
+// removePokers 返回从手牌 hand 中去掉 pokers 后剩下的牌，不修改 hand。
+func removePokers(hand, pokers []int) []int {
+	res := make([]int, 0, len(hand))
+	for _, poker := range hand {
+		shot := false
+		for _, p := range pokers {
+			if p == poker {
+				shot = true
+				break
+			}
+		}
+		if !shot {
+			res = append(res, poker)
+		}
+	}
+	return res
+}
+
+// handStrength 评估手牌的强度，用于机器人叫分。
+// 王炸记 8 分，单独的大王、小王记 4 分和 3 分，每张 2 记 2 分，每张 A 记 1 分，每个炸弹记 6 分；
+// 拆牌后少于 10 手时，每少一手再加 1 分。手牌多于 17 张的玩法按比例折算。
+func handStrength(rules common.RuleSet, hand []int) int {
+	var counts [common.RankRedJoker + 1]int
+	jokers := make([]int, 0, 4)
+	for _, poker := range hand {
+		rank := common.CardRank(poker)
+		counts[rank]++
+		if rank >= common.RankBlackJoker {
+			jokers = append(jokers, poker)
+		}
+	}
+	strength := 0
+	if rocket, err := rules.Classify(jokers); err == nil && rocket.Kind == common.KindRocket {
+		strength += 8
+	} else {
+		strength += counts[common.RankRedJoker]*4 + counts[common.RankBlackJoker]*3
+	}
+	strength += counts[common.Rank2]*2 + counts[common.RankA]
+	for rank := 0; rank < common.RankBlackJoker; rank++ {
+		if counts[rank] >= 4 {
+			strength += 6
+		}
+	}
+	if turns := common.Turns(rules, hand); turns < 10 {
+		strength += 10 - turns
+	}
+	return strength * 17 / rules.HandSize()
+}
+
+// planCallScore 根据手牌强度选择叫分，返回 0 表示不叫。
+// 强度达到 14、12、10 分时分别想叫 3、2、1 分，想叫的分不高于当前最高叫分 maxCallScore 时不叫。
+// 普通机器人评估手牌时带有少量随机误差；困难机器人不带误差，并把三张底牌的期望收益计入强度。
+func planCallScore(rules common.RuleSet, hand []int, maxCallScore int, level RobotLevel) int {
+	strength := handStrength(rules, hand)
+	if level == RobotHard {
+		strength++
+	} else {
+		strength += rand.Intn(5) - 2
+	}
+	score := 0
+	switch {
+	case strength >= 14:
+		score = 3
+	case strength >= 12:
+		score = 2
+	case strength >= 10:
+		score = 1
+	}
+	if score <= maxCallScore {
+		return 0
+	}
+	return score
+}
@@ -17,7 +17,7 @@ type TableId int
 const (
 
 	// GameWaitting represents the state of the game when the players are waiting to start playing.
-	GameWaitting = iota
+	GameWaitting TableState = iota
 
 	// GameCallScore 表示游戏状态“Call Score”的常量值
 	GameCallScore
@@ -30,13 +30,31 @@ const (
 )
 
 // Table 代表房间中的牌桌，包含牌桌的基本信息和状态。
+// State 只能通过 setState 按状态机的规则转换。
+// Rules、Bidding 是创建牌桌时从房间继承的玩法和叫地主方式。
+// Seats 是牌桌的座位，长度等于玩法规定的人数，空座位为 nil，按座位号顺时针轮流叫分和出牌，见 next。
+// TableClients 是按玩家 ID 索引的座位上的玩家，与 Seats 一起由 sit、unsit 维护，需要按座位顺序时使用 Seats。
+// Watchers 是牌桌上的观战者，他们不在 TableClients 中，只接收公开的消息，见 watch。
+// nextSeed、nextDeal 是通过 SetNextSeed、SetNextDeal 为下一局指定的种子和发牌，发牌后清空。
+// redeals 是所有人都不叫时连续重新发牌的次数，有人当上地主后清零，见 callEnd。
+// turnTimer 是当前回合的计时，turnDeadline 是其截止时间，turnSeq 在每次开始或停止计时时递增，用于识别过期的计时，见 startTurn。
 type Table struct {
 	Lock         sync.RWMutex
 	TableId      TableId
-	State        int
+	State        TableState
 	Creator      *Client
+	Rules        common.RuleSet
+	Bidding      BiddingMode
+	Seats        []*Client
 	TableClients map[UserId]*Client
+	Watchers     map[UserId]*Client
 	GameManage   *GameManage
+	nextSeed     *int64
+	nextDeal     *Deal
+	redeals      int
+	turnTimer    *time.Timer
+	turnDeadline time.Time
+	turnSeq      int
 }
 
 // GameManage 表示游戏管理的数据结构。
@@ -45,13 +63,26 @@ type Table struct {
 // GameManage 有以下字段:
 //
 // - Turn: 目前轮到的玩家。
-// - FirstCallScore: 每局轮转的玩家。
+// - FirstCallScore: 本局第一个叫分的玩家，每局按座位顺时针轮转。
 // - MaxCallScore: 最大叫分值。
 // - MaxCallScoreTurn: 叫分最高的玩家。
-// - LastShotClient: 上一次出牌的玩家。
+// - LastShotClient: 上一次出牌的玩家，一轮结束、由下一个玩家自由出牌时为 nil。
 // - Pokers: 当前玩家手中的扑克牌。
 // - LastShotPoker: 上一次出牌的牌。
+// - ShotPokers: 本局已经打出的所有牌。
 // - Multiple: 加倍倍数。
+// - Seed: 本局洗牌使用的种子，使用预先安排的发牌时为 0。
+// - Deal: 本局发出的牌。
+// - Replay: 本局的录像。
+// - StartTime: 本局发牌的时间。
+// - CallScores: 每个玩家叫的分，没有轮到叫分的玩家不在其中。
+// - Bombs: 每个玩家打出的炸弹和王炸的个数。
+// - Rockets: 本局打出的王炸个数。
+// - Plays: 每个玩家出牌（不含不出）的次数，用于判断春天和反春。
+// - Passes: 上一次出牌之后连续不出的玩家数。
+// - Caller: 抢地主玩法中叫地主的玩家。
+// - Robs: 抢地主玩法中抢地主的次数，每抢一次 Multiple 翻倍。
+// - RobFinal: 抢地主玩法中是否轮到叫地主的玩家最后再抢一次。
 type GameManage struct {
 	Turn             *Client
 	FirstCallScore   *Client //每局轮转
@@ -60,7 +91,45 @@ type GameManage struct {
 	LastShotClient   *Client
 	Pokers           []int
 	LastShotPoker    []int
+	ShotPokers       []int
 	Multiple         int //加倍
+	Seed             int64
+	Deal             Deal
+	Replay           *Replay
+	StartTime        time.Time
+	CallScores       map[UserId]int
+	Bombs            map[UserId]int
+	Rockets          int
+	Plays            map[UserId]int
+	Passes           int
+	Caller           *Client
+	Robs             int
+	RobFinal         bool
+}
+
+// Multiples 是一局结束时倍数的构成，随 ResGameOver 发给客户端，用于展示得分的由来。
+//
+// - CallScore: 地主的叫分，抢地主玩法为 1。
+// - Robs: 抢地主玩法中抢地主的次数。
+// - RobMultiple: 抢地主带来的倍数。
+// - Bombs: 打出的炸弹个数，不含王炸。
+// - Rockets: 打出的王炸个数。
+// - BombMultiple: 炸弹和王炸带来的倍数。
+// - Spring: 是否春天，地主获胜时两个农民都没有出过牌，倍数翻倍。
+// - AntiSpring: 是否反春，农民获胜时地主只出过第一手牌，倍数翻倍。
+// - Multiple: 最终的倍数，即 GameManage.Multiple。
+// - Score: 叫分乘以倍数，每个农民与地主输赢 入场费 * Score 的金币。
+type Multiples struct {
+	CallScore    int  `json:"call_score"`
+	Robs         int  `json:"robs"`
+	RobMultiple  int  `json:"rob_multiple"`
+	Bombs        int  `json:"bombs"`
+	Rockets      int  `json:"rockets"`
+	BombMultiple int  `json:"bomb_multiple"`
+	Spring       bool `json:"spring"`
+	AntiSpring   bool `json:"anti_spring"`
+	Multiple     int  `json:"multiple"`
+	Score        int  `json:"score"`
 }
 
 // allCalled 检查是否已调用牌桌中的所有客户端。
@@ -76,105 +145,346 @@ func (table *Table) allCalled() bool {
 // gameOver 一局结束，计算玩家的输赢情况并发送消息给每个玩家。
 // 接收参数：
 //   - table *Table: 牌桌对象
-//   - client *Client: 当前玩家对象
+//   - client *Client: 最先出完牌的玩家
 //
 // 逻辑：
-//  1. 计算玩家需要支付的筹码数量：入场费 * 最大叫分 * 当前局的倍数
-//  2. 设置牌桌状态为游戏结束状态
-//  3. 遍历牌桌的每一个客户端玩家
-//     - 初始化一个结果切片并将消息类型(common.ResGameOver)和当前玩家的ID添加到结果切片中
-//     - 如果当前玩家是比赛的胜利者，则将该玩家需要支付的筹码数量加倍再减100，并将结果添加到结果切片中
-//     - 否则，将该玩家需要支付的筹码数量添加到结果切片中
-//     - 遍历其他客户端玩家，将其他客户端玩家的ID和手牌添加到结果切片中
-//     - 将结果切片发送给当前玩家
-//  4. 记录一条调试日志，表示牌桌已结束游戏
+//  1. 按 multiples 判断春天和反春，计算倍数的构成
+//  2. 按 coinDeltas 计算每个玩家输赢的金币（已扣除入场费）
+//  3. 设置牌桌状态为游戏结束状态，计时随之停止
+//  4. 遍历牌桌的每一个客户端玩家
+//     - 初始化一个结果切片并将消息类型(common.ResGameOver)和获胜玩家的ID添加到结果切片中
+//     - 将该玩家本局输赢的金币添加到结果切片中
+//     - 按座位顺序遍历其他客户端玩家，将其他客户端玩家的ID和手牌添加到结果切片中
+//     - 最后添加倍数的构成 Multiples
+//     - 将结果切片记入录像并发送给当前玩家
+//  5. 向观战者发送所有玩家的手牌，观战者没有输赢的金币
+//  6. 在一个事务中保存本局录像、对局记录并结算金币，见 saveGame
+//  7. 本局中断线的玩家离开牌桌
+//  8. 记录一条调试日志，表示牌桌已结束游戏
 func (table *Table) gameOver(client *Client) {
-	coin := table.Creator.Room.EntranceFee * table.GameManage.MaxCallScore * table.GameManage.Multiple
-	table.State = GameEnd
-	for _, c := range table.TableClients {
-		res := []interface{}{common.ResGameOver, client.UserInfo.UserId}
-		if client == c {
-			res = append(res, coin*2-100)
-		} else {
-			res = append(res, coin)
-		}
-		for _, cc := range table.TableClients {
+	multiples := table.multiples(client)
+	deltas := table.coinDeltas(client)
+	table.setState(GameEnd)
+	players := table.players(nil)
+	for _, c := range players {
+		res := []interface{}{common.ResGameOver, client.UserInfo.UserId, deltas[c.UserInfo.UserId]}
+		for _, cc := range players {
 			if cc != c {
 				userPokers := make([]int, 0, len(cc.HandPokers)+1)
 				userPokers = append(append(userPokers, int(cc.UserInfo.UserId)), cc.HandPokers...)
 				res = append(res, userPokers)
 			}
 		}
+		res = append(res, multiples)
+		table.record(c.UserInfo.UserId, res)
 		c.sendMsg(res)
 	}
+	if len(table.Watchers) > 0 {
+		res := []interface{}{common.ResGameOver, client.UserInfo.UserId, 0}
+		for _, c := range players {
+			userPokers := make([]int, 0, len(c.HandPokers)+1)
+			res = append(res, append(append(userPokers, int(c.UserInfo.UserId)), c.HandPokers...))
+		}
+		table.sendWatchers(append(res, multiples))
+	}
+	table.saveGame(client, deltas)
+	offline := make([]*Client, 0)
+	for _, c := range table.TableClients {
+		if c.offline {
+			offline = append(offline, c)
+		}
+	}
+	for _, c := range offline {
+		c.leave()
+	}
 	logs.Debug("table[%d] game over", table.TableId)
 }
 
+// callScore 处理玩家 c 叫 score 分，0 表示不叫，调用方需持有牌桌的锁。
+// 不在叫分阶段、没有轮到 c 或者叫分不合法时返回拒绝的原因。
+// 叫分以 ResCallScore 广播，携带玩家 ID、叫分和叫分是否结束；有人叫 3 分或者所有人都叫过后叫分结束。
+func (table *Table) callScore(c *Client, score int) *requestError {
+	game := table.GameManage
+	if table.Bidding != BidScore {
+		return &requestError{common.ErrCodeWrongState, "这张牌桌是抢地主玩法"}
+	}
+	if table.State != GameCallScore {
+		logs.Debug("game call score at run time ,%v", table.State)
+		return &requestError{common.ErrCodeWrongState, "现在不是叫分阶段"}
+	}
+	if table.turnClient() != c || c.IsCalled {
+		logs.Debug("user [%v] call score turn err", c.UserInfo.Username)
+		return &requestError{common.ErrCodeNotYourTurn, "还没有轮到你叫分"}
+	}
+	if score > 0 && score <= game.MaxCallScore || score < 0 || score > 3 {
+		logs.Error("player[%d] call score[%d] cheat", c.UserInfo.UserId, score)
+		return &requestError{common.ErrCodeInvalidScore, "叫分必须高于当前的最高叫分"}
+	}
+	game.Turn = table.next(c)
+	game.CallScores[c.UserInfo.UserId] = score
+	if score > game.MaxCallScore {
+		game.MaxCallScore = score
+		game.MaxCallScoreTurn = c
+	}
+	c.IsCalled = true
+	callEnd := score == 3 || table.allCalled()
+	table.broadcast([]interface{}{common.ResCallScore, c.UserInfo.UserId, score, callEnd})
+	if callEnd {
+		logs.Debug("call score end")
+		table.callEnd()
+	} else {
+		table.startTurn()
+	}
+	return nil
+}
+
+// shotPoker 处理玩家 c 出牌 shotPokers，调用方需持有牌桌的锁。
+// 不在出牌阶段、没有轮到 c、牌不在手中、牌型不合法或者没有压过上家时返回拒绝的原因。
+// 出牌以 ResShotPoker 广播，c 出完手牌时本局结束。
+func (table *Table) shotPoker(c *Client, shotPokers []int) *requestError {
+	game := table.GameManage
+	if table.State != GamePlaying {
+		return &requestError{common.ErrCodeWrongState, "现在不是出牌阶段"}
+	}
+	if game.Turn != c {
+		logs.Error("shot poker err,not your [%d] turn", c.UserInfo.UserId)
+		return &requestError{common.ErrCodeNotYourTurn, "还没有轮到你出牌"}
+	}
+	for i, poker := range shotPokers {
+		inHand := false
+		for _, handPoker := range c.HandPokers {
+			if handPoker == poker {
+				inHand = true
+				break
+			}
+		}
+		for _, shotPoker := range shotPokers[:i] {
+			if shotPoker == poker {
+				inHand = false
+				break
+			}
+		}
+		if !inHand {
+			logs.Warn("player[%d] play non-exist poker", c.UserInfo.UserId)
+			return &requestError{common.ErrCodeNotInHand, "出的牌不在手中"}
+		}
+	}
+	lastShotPoker := game.LastShotPoker
+	if table.isLead(c) {
+		lastShotPoker = nil
+	}
+	hand, err := common.ValidatePlay(table.Rules, lastShotPoker, shotPokers)
+	if err != nil {
+		logs.Warn("player[%d] shot poker %v against last shot poker %v err: %v", c.UserInfo.UserId, shotPokers, lastShotPoker, err)
+		return playError(err)
+	}
+	game.Multiple *= table.Rules.Multiple(hand)
+	if hand.IsBomb() {
+		game.Bombs[c.UserInfo.UserId]++
+	}
+	if hand.Kind == common.KindRocket {
+		game.Rockets++
+	}
+	game.Plays[c.UserInfo.UserId]++
+	game.Passes = 0
+	game.LastShotClient = c
+	game.LastShotPoker = shotPokers
+	game.ShotPokers = append(game.ShotPokers, shotPokers...)
+	game.Turn = table.next(c)
+	for _, shotPoker := range shotPokers {
+		for i, poker := range c.HandPokers {
+			if shotPoker == poker {
+				copy(c.HandPokers[i:], c.HandPokers[i+1:])
+				c.HandPokers = c.HandPokers[:len(c.HandPokers)-1]
+				break
+			}
+		}
+	}
+	for _, client := range table.TableClients {
+		client.hints = nil
+	}
+	table.broadcast([]interface{}{common.ResShotPoker, c.UserInfo.UserId, shotPokers})
+	if len(c.HandPokers) == 0 {
+		table.gameOver(c)
+	} else {
+		table.startTurn()
+	}
+	return nil
+}
+
+// isLead 判断是否轮到玩家 c 自由出牌：本局还没有人出牌，一轮结束，或者其他玩家都没有压过 c 上一次出的牌。
+func (table *Table) isLead(c *Client) bool {
+	last := table.GameManage.LastShotClient
+	return last == nil || last == c
+}
+
+// pass 处理玩家 c 不出，调用方需持有牌桌的锁。
+// 不是 c 的回合或者 c 需要自由出牌时不能不出，返回拒绝的原因。
+// 连续其他所有玩家都不出时一轮结束，清空上一次出的牌，由最后出牌的玩家自由出牌。
+// 不出会以 ResPass 广播，携带玩家 ID 和这一轮是否结束。
+func (table *Table) pass(c *Client) *requestError {
+	game := table.GameManage
+	if table.State != GamePlaying {
+		return &requestError{common.ErrCodeWrongState, "现在不是出牌阶段"}
+	}
+	if game.Turn != c {
+		logs.Error("player[%d] pass out of turn", c.UserInfo.UserId)
+		return &requestError{common.ErrCodeNotYourTurn, "还没有轮到你出牌"}
+	}
+	if table.isLead(c) {
+		logs.Warn("player[%d] can not pass when leading", c.UserInfo.UserId)
+		return &requestError{common.ErrCodeCannotPass, "自由出牌时不能不出"}
+	}
+	game.Passes++
+	game.Turn = table.next(c)
+	roundEnd := game.Passes >= len(table.TableClients)-1
+	if roundEnd {
+		game.LastShotClient = nil
+		game.LastShotPoker = game.LastShotPoker[:0]
+		game.Passes = 0
+	}
+	for _, client := range table.TableClients {
+		client.hints = nil
+	}
+	table.broadcast([]interface{}{common.ResPass, c.UserInfo.UserId, roundEnd})
+	table.startTurn()
+	return nil
+}
+
+// multiples 在一局结束时判断春天和反春，春天或反春时 GameManage.Multiple 翻倍，并返回倍数的构成。
+// winner 是最先出完牌的玩家。
+func (table *Table) multiples(winner *Client) Multiples {
+	game := table.GameManage
+	m := Multiples{
+		CallScore:   game.MaxCallScore,
+		Robs:        game.Robs,
+		RobMultiple: 1 << uint(game.Robs),
+		Rockets:     game.Rockets,
+	}
+	m.BombMultiple = game.Multiple / m.RobMultiple
+	for _, n := range game.Bombs {
+		m.Bombs += n
+	}
+	m.Bombs -= m.Rockets
+	farmerPlays, landlordPlays := 0, 0
+	for _, c := range table.TableClients {
+		if c.UserInfo.Role == RoleLandlord {
+			landlordPlays += game.Plays[c.UserInfo.UserId]
+		} else {
+			farmerPlays += game.Plays[c.UserInfo.UserId]
+		}
+	}
+	if winner.UserInfo.Role == RoleLandlord {
+		m.Spring = farmerPlays == 0
+	} else {
+		m.AntiSpring = landlordPlays == 1
+	}
+	if m.Spring || m.AntiSpring {
+		game.Multiple *= 2
+	}
+	m.Multiple = game.Multiple
+	m.Score = m.CallScore * m.Multiple
+	return m
+}
+
 // callEnd 在调用阶段结束后推进游戏状态。
-// 它将表状态设置为 GamePlaying 并更新第一次调用得分。
-// 如果之前没有最大调用分数，则将创建者设置为最大调用分数回合，并将调用分数设置为1。
+// 如果所有人都不叫，按房间的 AllPass 处理：重新发牌时见 redeal，连续重新发牌达到 MaxRedeals 次后
+// 强制本局第一个叫分的玩家当地主；强制当地主时叫分按 1 分计算。
+// 它将表状态设置为 GamePlaying，并将下一局第一个叫分的玩家轮转到顺时针方向的下一个座位。
 // 然后将地主设置为最大呼叫分数回合，并将其角色更改为 RoleLandlord。
 // 轮到地主了。
 // 地主手牌扑克更新为游戏扑克。
 // 最后，它使用 show poker 命令、房东的用户 ID 和游戏的扑克向所有牌桌客户端发送响应。
 func (table *Table) callEnd() {
-	table.State = GamePlaying
-	table.GameManage.FirstCallScore = table.GameManage.FirstCallScore.Next
-	if table.GameManage.MaxCallScoreTurn == nil || table.GameManage.MaxCallScore == 0 {
-		table.GameManage.MaxCallScoreTurn = table.Creator
-		table.GameManage.MaxCallScore = 1
-		//return
+	game := table.GameManage
+	if game.MaxCallScoreTurn == nil || game.MaxCallScore == 0 {
+		room := table.Creator.Room
+		switch {
+		case room.AllPass == AllPassRedeal && table.redeals < room.MaxRedeals:
+			table.redeal()
+			return
+		case room.AllPass == AllPassCreator:
+			game.MaxCallScoreTurn = table.Creator
+		default:
+			game.MaxCallScoreTurn = game.FirstCallScore
+		}
+		logs.Debug("table[%d] all pass, force [%d] to be landlord", table.TableId, game.MaxCallScoreTurn.UserInfo.UserId)
+		game.MaxCallScore = 1
 	}
+	table.redeals = 0
+	table.setState(GamePlaying)
+	game.FirstCallScore = table.next(game.FirstCallScore)
 	landLord := table.GameManage.MaxCallScoreTurn
 	landLord.UserInfo.Role = RoleLandlord
 	table.GameManage.Turn = landLord
 	for _, poker := range table.GameManage.Pokers {
 		landLord.HandPokers = append(landLord.HandPokers, poker)
 	}
-	res := []interface{}{common.ResShowPoker, landLord.UserInfo.UserId, table.GameManage.Pokers}
+	table.broadcast([]interface{}{common.ResShowPoker, landLord.UserInfo.UserId, table.GameManage.Pokers})
+	table.startTurn()
+}
+
+// redeal 在所有人都不叫时重新发牌，由顺时针方向的下一个玩家第一个叫分，调用方需持有牌桌的锁。
+// 重新发牌以 ResRedeal 广播，携带连续重新发牌的次数和新的第一个叫分的玩家 ID，之后与新的一局一样发牌，没有打完的这一局不保存录像。
+func (table *Table) redeal() {
+	table.redeals++
+	game := table.GameManage
+	table.GameManage = &GameManage{
+		FirstCallScore: table.next(game.FirstCallScore),
+		Pokers:         game.Pokers[:0],
+		LastShotPoker:  game.LastShotPoker[:0],
+		Multiple:       1,
+	}
 	for _, c := range table.TableClients {
-		c.sendMsg(res)
+		c.reset()
+	}
+	logs.Debug("table[%d] all pass, redeal %d", table.TableId, table.redeals)
+	table.broadcast([]interface{}{common.ResRedeal, table.redeals, table.GameManage.FirstCallScore.UserInfo.UserId})
+	if table.setState(GameCallScore) {
+		table.dealPoker()
+	}
+}
+
+// broadcast 将消息记入本局录像，并发送给牌桌上的所有客户端和观战者。
+func (table *Table) broadcast(msg []interface{}) {
+	table.record(0, msg)
+	for _, c := range table.TableClients {
+		c.sendMsg(msg)
 	}
+	table.sendWatchers(msg)
 }
 
 // joinTable 客户端加入牌桌。
 // 开始临界区，结束临界区，无论我们如何退出这个函数
-// 检查牌桌是否已满，记录错误并返回（如果已满）
 // 记录用户请求加入
 // 检查用户是否已经在牌桌中，如果用户已在牌桌上，则记录错误并返回
+// 让用户坐到编号最小的空座位上，没有空座位时记录错误并返回
 // 将牌桌分配给客户端
 // 将客户端标记为就绪
-// 将新用户分配给某个现有用户的“下一个”字段
-// 将用户添加到牌桌中
 // 执行用户同步
-// 如果牌桌已满，有 3 名玩家，新用户的“下一个”是牌桌创建者，改变牌桌状态，发牌
+// 如果牌桌已坐满，改变牌桌状态，发牌；上一局结束后有玩家离开、新的玩家坐满时先重置牌局
 // 如果房间允许机器人并且牌桌未满，添加机器人玩家，记录机器人加入成功
 func (table *Table) joinTable(c *Client) {
-	table.Lock.Lock()                // 开始临界区
-	defer table.Lock.Unlock()        // 结束临界区，无论我们如何退出这个函数
-	if len(table.TableClients) > 2 { // 检查牌桌是否已满
-		logs.Error("Player[%d] JOIN Table[%d] FULL", c.UserInfo.UserId, table.TableId) // 记录错误并返回（如果已满）
-		return
-	}
+	table.Lock.Lock()                                                                       // 开始临界区
+	defer table.Lock.Unlock()                                                               // 结束临界区，无论我们如何退出这个函数
 	logs.Debug("[%v] user [%v] request join table", c.UserInfo.UserId, c.UserInfo.Username) // 记录用户请求加入
 	if _, ok := table.TableClients[c.UserInfo.UserId]; ok {                                 // 检查用户是否已经在牌桌中
 		logs.Error("[%v] user [%v] already in this table", c.UserInfo.UserId, c.UserInfo.Username) // 如果用户已在牌桌上，则记录错误并返回
 		return
 	}
-	c.Table = table                             // 将牌桌分配给客户端
-	c.Ready = true                              // 将客户端标记为就绪
-	for _, client := range table.TableClients { // 将新用户分配给某个现有用户的“下一个”字段
-		if client.Next == nil {
-			client.Next = c
-			break
-		}
+	if !table.sit(c) { // 让用户坐到编号最小的空座位上
+		logs.Error("Player[%d] JOIN Table[%d] FULL", c.UserInfo.UserId, table.TableId) // 没有空座位时记录错误并返回
+		return
 	}
-	table.TableClients[c.UserInfo.UserId] = c // 将用户添加到牌桌中
-	table.syncUser()                          // 执行用户同步
-	if len(table.TableClients) == 3 {         // 如果牌桌已满，有 3 名玩家
-		c.Next = table.Creator      // 新用户的“下一个”是牌桌创建者
-		table.State = GameCallScore // 改变牌桌状态
-		table.dealPoker()           // 发牌
+	c.Table = table   // 将牌桌分配给客户端
+	c.Ready = true    // 将客户端标记为就绪
+	table.syncUser()  // 执行用户同步
+	if table.full() { // 如果牌桌已坐满
+		if table.State == GameEnd { // 上一局结束后有玩家离开，新的玩家坐满时重置牌局
+			table.reset()
+		} else if table.setState(GameCallScore) { // 改变牌桌状态
+			table.dealPoker() // 发牌
+		}
 	} else if c.Room.AllowRobot { // 如果房间允许机器人并且牌桌未满
 		go table.addRobot(c.Room)   // 添加机器人玩家
 		logs.Debug("robot join ok") // 记录机器人加入成功
@@ -182,19 +492,23 @@ func (table *Table) joinTable(c *Client) {
 }
 
 // addRobot 加入机器人.
-// 它创建一个机器人客户端并将其加入到牌桌中，当牌桌中的客户端数量少于3个时。
+// 它创建一个机器人客户端并将其加入到牌桌中，当牌桌还有空座位时。
 // 机器人客户端具有以下属性:
 // - Room: 牌桌所在的房间.
 // - HandPokers: 机器人客户端持有的扑克牌.
 // - UserInfo: 机器人客户端的用户信息，包括用户ID、用户名和金币数.
 // - IsRobot: 指示客户端是否是机器人.
-// - toRobot: 用于接收来自机器人客户端的消息的通道.
+// - toRobot: 用于接收来自机器人客户端的消息的通道，容量为 robotInboxSize.
 // - toServer: 用于向机器人客户端发送消息的通道.
+// - strategy: 机器人的决策逻辑，由房间配置的难度 RobotLevel 决定.
 // 如果成功创建并加入机器人客户端，将启动一个 goroutine 来运行机器人客户端的逻辑.
 // 使用 `table.joinTable` 方法将机器人客户端加入牌桌.
 func (table *Table) addRobot(room *Room) {
 	logs.Debug("robot [%v] join table", fmt.Sprintf("ROBOT-%d", len(table.TableClients)))
-	if len(table.TableClients) < 3 {
+	table.Lock.RLock()
+	full := table.full()
+	table.Lock.RUnlock()
+	if !full {
 		client := &Client{
 			Room:       room,
 			HandPokers: make([]int, 0, 21),
@@ -204,8 +518,9 @@ func (table *Table) addRobot(room *Room) {
 				Coin:     10000,
 			},
 			IsRobot:  true,
-			toRobot:  make(chan []interface{}, 3),
+			toRobot:  make(chan []interface{}, robotInboxSize),
 			toServer: make(chan []interface{}, 3),
+			strategy: newRobotStrategy(room.RobotLevel),
 		}
 		go client.runRobot()
 		table.joinTable(client)
@@ -225,40 +540,112 @@ func (table *Table) getRobotID() (robot UserId) {
 	return
 }
 
-// dealPoker 发牌，首先将所有的扑克牌加入游戏管理的牌组，然后洗牌，再将扑克牌发给玩家，
-// 每个玩家发17张牌，最后将玩家的手牌按升序排列，并发送给客户端。
+// dealPoker 发牌。
+// 如果通过 SetNextDeal 指定了发牌则直接使用，否则用种子洗牌后发牌，种子默认由 crypto/rand 生成，
+// 也可以通过 SetNextSeed 指定，本局的种子和发出的牌记录在 GameManage 中。
+// 从本局第一个叫分的玩家开始按座位顺时针依次发给每个玩家，剩下的作为底牌，
+// 最后将玩家的手牌按升序排列，连同牌桌的叫地主方式发送给客户端，观战者收到以 common.HiddenPoker 代替的手牌，然后开始第一个叫分的玩家的计时。
+// 每局的录像从发牌开始录制。
 func (table *Table) dealPoker() {
 	logs.Debug("deal poker")
-	table.GameManage.Pokers = make([]int, 0)
-	for i := 0; i < 54; i++ {
-		table.GameManage.Pokers = append(table.GameManage.Pokers, i)
-	}
-	table.ShufflePokers()
-	for i := 0; i < 17; i++ {
-		for _, client := range table.TableClients {
-			client.HandPokers = append(client.HandPokers, table.GameManage.Pokers[len(table.GameManage.Pokers)-1])
-			table.GameManage.Pokers = table.GameManage.Pokers[:len(table.GameManage.Pokers)-1]
+	game := table.GameManage
+	if table.nextDeal != nil {
+		game.Seed, game.Deal = 0, *table.nextDeal
+	} else {
+		game.Seed = newSeed()
+		if table.nextSeed != nil {
+			game.Seed = *table.nextSeed
 		}
+		game.Deal = NewDeal(table.Rules, game.Seed)
 	}
-	response := make([]interface{}, 0, 3)
-	response = append(append(append(response, common.ResDealPoker), table.GameManage.FirstCallScore.UserInfo.UserId), nil)
-	for _, client := range table.TableClients {
+	table.nextSeed, table.nextDeal = nil, nil
+	logs.Debug("table[%d] deal poker with seed %d", table.TableId, game.Seed)
+	game.Replay = table.newReplay()
+	game.StartTime = time.Now()
+	game.CallScores = make(map[UserId]int, len(game.Deal.Hands))
+	game.Bombs = make(map[UserId]int, len(game.Deal.Hands))
+	game.Plays = make(map[UserId]int, len(game.Deal.Hands))
+
+	game.Pokers = append(game.Pokers[:0], game.Deal.Bottom...)
+	response := make([]interface{}, 0, 4)
+	response = append(append(append(response, common.ResDealPoker), game.FirstCallScore.UserInfo.UserId), nil, table.Bidding)
+	for i, client := range table.players(game.FirstCallScore) {
+		client.HandPokers = append(client.HandPokers[:0], game.Deal.Hands[i]...)
 		sort.Ints(client.HandPokers)
-		response[len(response)-1] = client.HandPokers
+		response[2] = client.HandPokers
+		table.record(client.UserInfo.UserId, response)
 		client.sendMsg(response)
 	}
+	if len(table.Watchers) > 0 && len(game.Deal.Hands) > 0 {
+		table.sendWatchers([]interface{}{common.ResDealPoker, game.FirstCallScore.UserInfo.UserId, hiddenPokers(len(game.Deal.Hands[0])), table.Bidding})
+	}
+	table.startTurn()
 }
 
-// chat 将消息发送给牌桌上的所有客户端
+// SetNextSeed 指定下一局洗牌使用的种子，用于复现牌局。
+func (table *Table) SetNextSeed(seed int64) {
+	table.Lock.Lock()
+	defer table.Lock.Unlock()
+	table.nextSeed = &seed
+}
+
+// SetNextDeal 指定下一局发出的牌，不再洗牌，用于测试、复盘和残局。
+// 发出的牌与牌桌的玩法不符时返回 ErrInvalidDeal。
+func (table *Table) SetNextDeal(deal Deal) error {
+	if err := deal.validate(table.Rules); err != nil {
+		return err
+	}
+	table.Lock.Lock()
+	defer table.Lock.Unlock()
+	table.nextDeal = &deal
+	return nil
+}
+
+// chat 将消息发送给牌桌上的所有客户端和观战者
 func (table *Table) chat(client *Client, msg string) {
 	res := []interface{}{common.ResChat, client.UserInfo.UserId, msg}
 	for _, c := range table.TableClients {
 		c.sendMsg(res)
 	}
+	table.sendWatchers(res)
+}
+
+// restart 在牌局结束后所有玩家都准备好时重新开始，调用方需持有牌桌的锁。
+// 重新发牌之前检查每个玩家的金币，余额已经不满足房间要求的玩家离开牌桌；
+// 允许机器人的房间由机器人补上离开的玩家留下的空座位，机器人平时只在有玩家入座时加入。
+func (table *Table) restart() {
+	if table.State != GameEnd || table.Creator == nil {
+		// 牌桌已经重新开始或者已经解散
+		return
+	}
+	for _, c := range table.TableClients {
+		if !c.Ready {
+			return
+		}
+	}
+	left, humans := false, 0
+	for _, c := range table.players(nil) {
+		if !c.allowRoom(c.Room) {
+			c.leave()
+			c.Table = nil
+			left = true
+		} else if !c.IsRobot {
+			humans++
+		}
+	}
+	if left && humans == 0 {
+		// 只剩下机器人，牌桌已经解散
+		return
+	}
+	logs.Debug("restart")
+	table.reset()
+	if room := table.Creator.Room; room.AllowRobot && !table.full() {
+		go table.addRobot(room)
+	}
 }
 
 // reset 重置牌桌状态和游戏管理信息，发送重新开始消息到创建者客户端，并重置
-// 所有牌桌客户端，如果牌桌客户端数量为3，则重新发牌。
+// 所有牌桌客户端，牌桌回到等待状态，如果牌桌已坐满，则开始叫分并重新发牌，否则等待玩家入座。
 func (table *Table) reset() {
 	table.GameManage = &GameManage{
 		FirstCallScore:   table.GameManage.FirstCallScore,
@@ -270,43 +657,38 @@ func (table *Table) reset() {
 		LastShotPoker:    table.GameManage.LastShotPoker[:0],
 		Multiple:         1,
 	}
-	table.State = GameCallScore
 	if table.Creator != nil {
 		table.Creator.sendMsg([]interface{}{common.ResRestart})
 	}
 	for _, c := range table.TableClients {
 		c.reset()
 	}
-	if len(table.TableClients) == 3 {
+	if table.setState(GameWaitting) && table.full() && table.setState(GameCallScore) {
 		table.dealPoker()
 	}
 }
 
-// ShufflePokers 实现对牌局中的扑克进行洗牌操作。
-func (table *Table) ShufflePokers() {
-	logs.Debug("ShufflePokers")
-	r := rand.New(rand.NewSource(time.Now().Unix()))
-	i := len(table.GameManage.Pokers)
-	for i > 0 {
-		randIndex := r.Intn(i)
-		table.GameManage.Pokers[i-1], table.GameManage.Pokers[randIndex] = table.GameManage.Pokers[randIndex], table.GameManage.Pokers[i-1]
-		i--
-	}
-}
-
-// syncUser 同步用户信息，将牌桌中的用户信息发送给所有客户端。
+// syncUser 同步用户信息，将牌桌中按座位号排列的玩家和观战者分别作为两个列表发送给所有客户端和观战者。
+// 空座位的玩家 ID 为 emptySeat。
 func (table *Table) syncUser() {
 	logs.Debug("sync user")
 	response := make([]interface{}, 0, 3)
 	response = append(append(response, common.ResJoinTable), table.TableId)
-	tableUsers := make([][2]interface{}, 0, 2)
-	current := table.Creator
-	for i := 0; i < len(table.TableClients); i++ {
+	tableUsers := make([][2]interface{}, 0, len(table.Seats))
+	for _, current := range table.Seats {
+		if current == nil {
+			tableUsers = append(tableUsers, [2]interface{}{emptySeat, ""})
+			continue
+		}
 		tableUsers = append(tableUsers, [2]interface{}{current.UserInfo.UserId, current.UserInfo.Username})
-		current = current.Next
 	}
-	response = append(response, tableUsers)
+	watchers := make([][2]interface{}, 0, len(table.Watchers))
+	for _, watcher := range table.Watchers {
+		watchers = append(watchers, [2]interface{}{watcher.UserInfo.UserId, watcher.UserInfo.Username})
+	}
+	response = append(response, tableUsers, watchers)
 	for _, client := range table.TableClients {
 		client.sendMsg(response)
 	}
+	table.sendWatchers(response)
 }
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+	"time"
+)
+
+// autoPlayDelay 是托管的玩家轮到时，由机器人策略代为叫分或出牌之前等待的时间。
+const autoPlayDelay = time.Second
+
+// 牌局结束或者牌桌回到等待状态时停止计时。
+func init() {
+	SubscribeState(func(table *Table, from, to TableState) {
+		if !to.inGame() {
+			table.stopTurn()
+		}
+	})
+}
+
+// turnClient 返回当前轮到的玩家：叫分阶段还没有人叫分时是第一个叫分的玩家，不在叫分或出牌阶段时为 nil。
+func (table *Table) turnClient() *Client {
+	switch table.State {
+	case GameCallScore:
+		if table.GameManage.Turn == nil {
+			return table.GameManage.FirstCallScore
+		}
+		return table.GameManage.Turn
+	case GamePlaying:
+		return table.GameManage.Turn
+	}
+	return nil
+}
+
+// startTurn 在轮到下一个玩家时开始计时，调用方需持有牌桌的锁。
+// 计时的长度由房间的 TurnTimeout 决定，为 0 时不计时；托管的玩家只等待 autoPlayDelay。
+// 开始计时时以 ResTurn 通知牌桌上的真人玩家和观战者，携带轮到的玩家 ID、剩余秒数和截止时间（Unix 毫秒）。
+// 超时后由 turnTimeout 代为操作。
+func (table *Table) startTurn() {
+	table.stopTurn()
+	c := table.turnClient()
+	if c == nil || c.Room == nil {
+		return
+	}
+	timeout := c.Room.TurnTimeout
+	if c.autoPlay {
+		timeout = autoPlayDelay
+	}
+	if timeout <= 0 {
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	table.turnDeadline = deadline
+	res := []interface{}{common.ResTurn, c.UserInfo.UserId, int(timeout / time.Second), deadline.UnixNano() / int64(time.Millisecond)}
+	for _, client := range table.TableClients {
+		if !client.IsRobot {
+			client.sendMsg(res)
+		}
+	}
+	table.sendWatchers(res)
+	seq := table.turnSeq
+	table.turnTimer = time.AfterFunc(timeout, func() {
+		table.turnTimeout(c, seq)
+	})
+}
+
+// stopTurn 停止当前的计时，已经触发但还没有执行的超时也会被忽略。调用方需持有牌桌的锁。
+func (table *Table) stopTurn() {
+	table.turnSeq++
+	table.turnDeadline = time.Time{}
+	if table.turnTimer != nil {
+		table.turnTimer.Stop()
+		table.turnTimer = nil
+	}
+}
+
+// turnTimeout 在玩家 c 的回合超时后代为操作，seq 用于忽略已经结束的回合的计时。
+// 没有托管的玩家由 fallbackAct 执行总是合法的操作；托管的玩家由机器人策略决定，策略的操作被拒绝时也改用 fallbackAct。
+// 专家机器人的策略可能要搜索一段时间，托管的玩家出牌时在释放牌桌的锁之后再交给策略，重新加锁后确认仍然轮到 c 才出牌。
+// 玩家连续超时达到房间的 AutoPlayAfter 次后进入托管。
+func (table *Table) turnTimeout(c *Client, seq int) {
+	defer func() {
+		if err := recover(); err != nil {
+			logs.Error("table[%d] turn timeout panic: %v", table.TableId, err)
+		}
+	}()
+	view, search := table.timeoutAct(c, seq)
+	if !search {
+		return
+	}
+	pokers := c.strategy.Play(view)
+	table.Lock.Lock()
+	defer table.Lock.Unlock()
+	if seq != table.turnSeq || table.turnClient() != c {
+		return
+	}
+	if err := table.playPokers(c, pokers); err != nil {
+		logs.Warn("player[%d] auto play act err: %s", c.UserInfo.UserId, err.Reason)
+		table.fallbackAct(c)
+	}
+}
+
+// timeoutAct 在持有牌桌的锁时处理玩家 c 的超时：累计超时次数，叫分阶段代为叫分或抢地主，没有托管时执行 fallbackAct。
+// 托管的玩家轮到出牌时不在这里出牌，返回 c 视角的牌局视图和 true，由 turnTimeout 交给机器人策略。
+func (table *Table) timeoutAct(c *Client, seq int) (view GameView, search bool) {
+	table.Lock.Lock()
+	defer table.Lock.Unlock()
+	if seq != table.turnSeq || table.turnClient() != c {
+		return
+	}
+	if !c.autoPlay {
+		c.timeouts++
+		logs.Debug("player[%d] turn timeout %d times", c.UserInfo.UserId, c.timeouts)
+		if n := c.Room.AutoPlayAfter; n > 0 && c.timeouts >= n && !c.IsRobot {
+			table.setAutoPlay(c, true)
+		}
+	}
+	if c.autoPlay {
+		if table.State == GamePlaying {
+			return c.gameView(), true
+		}
+		err := table.strategyBid(c)
+		if err == nil {
+			return
+		}
+		logs.Warn("player[%d] auto play act err: %s", c.UserInfo.UserId, err.Reason)
+	}
+	table.fallbackAct(c)
+	return
+}
+
+// strategyBid 由机器人策略代托管的玩家 c 叫分或者叫地主、抢地主。调用方需持有牌桌的锁。
+func (table *Table) strategyBid(c *Client) *requestError {
+	if table.Bidding == BidRob {
+		return table.rob(c, c.wantRob())
+	}
+	return table.callScore(c, c.strategy.Bid(c.gameView()))
+}
+
+// playPokers 代玩家 c 打出 pokers，pokers 为空时不出。调用方需持有牌桌的锁。
+func (table *Table) playPokers(c *Client, pokers []int) *requestError {
+	if len(pokers) > 0 {
+		return table.shotPoker(c, pokers)
+	}
+	return table.pass(c)
+}
+
+// fallbackAct 代玩家 c 执行总是合法的操作：叫分阶段不叫（抢地主玩法不叫、不抢），
+// 出牌阶段需要自由出牌时出最小的一张单牌，否则不出。仍然失败时重新计时，避免牌桌停住。调用方需持有牌桌的锁。
+func (table *Table) fallbackAct(c *Client) {
+	var err *requestError
+	switch table.State {
+	case GameCallScore:
+		if table.Bidding == BidRob {
+			err = table.rob(c, false)
+		} else {
+			err = table.callScore(c, 0)
+		}
+	case GamePlaying:
+		if table.isLead(c) {
+			err = table.shotPoker(c, []int{smallestPoker(c.HandPokers)})
+		} else {
+			err = table.pass(c)
+		}
+	}
+	if err != nil {
+		logs.Error("player[%d] turn timeout act err: %s", c.UserInfo.UserId, err.Reason)
+		table.startTurn()
+	}
+}
+
+// setAutoPlay 设置玩家 c 是否托管，并以 ResAutoPlay 通知牌桌上的所有玩家，调用方需持有牌桌的锁。
+// 托管时由房间难度（不允许机器人的房间为普通难度）的机器人策略代为叫分和出牌，取消托管时清零超时次数。
+// 玩家可以通过 ReqAutoPlay 主动托管，超时太多次或者断线时也会被托管。
+func (table *Table) setAutoPlay(c *Client, on bool) {
+	if c.autoPlay == on {
+		return
+	}
+	c.autoPlay = on
+	c.timeouts = 0
+	if on && c.strategy == nil {
+		level := c.Room.RobotLevel
+		if !c.Room.AllowRobot {
+			// 不允许机器人的房间没有配置难度
+			level = RobotNormal
+		}
+		c.strategy = newRobotStrategy(level)
+	}
+	table.broadcast([]interface{}{common.ResAutoPlay, c.UserInfo.UserId, on})
+}
+
+// smallestPoker 返回手牌中点数最小的一张牌。
+func smallestPoker(pokers []int) int {
+	smallest := pokers[0]
+	for _, poker := range pokers[1:] {
+		if common.CardRank(poker) < common.CardRank(smallest) {
+			smallest = poker
+		}
+	}
+	return smallest
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"github.com/astaxie/beego/logs"
+	"landlord/common"
+)
+
+// watch 让客户端 c 以观战者的身份进入牌桌，调用方需持有牌桌的锁。
+// 观战者不在 TableClients 中，不影响座位和发牌，只接收叫分、出牌、结算等公开的消息，玩家的手牌对观战者不可见。
+// 进入后以 ResWatchTable 回复牌桌 ID 和观战者视角的牌局快照，并以 ResJoinTable 同步牌桌上的玩家和观战者。
+func (table *Table) watch(c *Client) *requestError {
+	if _, ok := table.TableClients[c.UserInfo.UserId]; ok {
+		return &requestError{common.ErrCodeWrongState, "已经在这张牌桌上"}
+	}
+	logs.Debug("user [%d] watch table [%d]", c.UserInfo.UserId, table.TableId)
+	c.watching = table
+	table.Watchers[c.UserInfo.UserId] = c
+	c.sendMsg([]interface{}{common.ResWatchTable, table.TableId, table.snapshot(nil)})
+	table.syncUser()
+	return nil
+}
+
+// unwatch 让观战者 c 离开牌桌，调用方需持有牌桌的锁。
+func (table *Table) unwatch(c *Client) {
+	if table.Watchers[c.UserInfo.UserId] != c {
+		return
+	}
+	logs.Debug("user [%d] stop watching table [%d]", c.UserInfo.UserId, table.TableId)
+	delete(table.Watchers, c.UserInfo.UserId)
+	c.watching = nil
+	table.syncUser()
+}
+
+// stopWatching 停止观战，没有在观战时什么也不做。
+func (c *Client) stopWatching() {
+	table := c.watching
+	if table == nil {
+		return
+	}
+	table.Lock.Lock()
+	defer table.Lock.Unlock()
+	table.unwatch(c)
+}
+
+// sendWatchers 将公开的消息发送给牌桌上的所有观战者。
+func (table *Table) sendWatchers(msg []interface{}) {
+	for _, c := range table.Watchers {
+		c.sendMsg(msg)
+	}
+}
+
+// hiddenPokers 返回 n 张以 common.HiddenPoker 代替的牌，用于向观战者隐藏玩家的手牌。
+func hiddenPokers(n int) []int {
+	pokers := make([]int, n)
+	for i := range pokers {
+		pokers[i] = common.HiddenPoker
+	}
+	return pokers
+}
@@ -93,6 +93,14 @@ PG.Preloader = {
 
 PG.MainMenu = {
     create: function () {
+        // 通过 /?replay=录像ID&speed=倍速 打开页面时直接播放录像
+        var replay = window.location.search.match(/[?&]replay=(\d+)/);
+        if (replay && !PG.replayStarted) {
+            PG.replayStarted = true;
+            var speed = window.location.search.match(/[?&]speed=([\d.]+)/);
+            this.state.start('Game', true, false, 0, {id: parseInt(replay[1]), speed: speed ? parseFloat(speed[1]) : 1});
+            return;
+        }
         this.stage.backgroundColor = '#182d3b';
         var bg = this.game.add.sprite(this.game.width / 2, 0, 'bg');
         bg.anchor.set(0.5, 0);
@@ -101,10 +109,25 @@ PG.MainMenu = {
         aiRoom.anchor.set(0.5);
         this.game.world.add(aiRoom);
 
+        // 快速开始旁边的文字链接进入专家机器人房间
+        var linkStyle = {font: "24px Arial", fill: "#ffd700"};
+        var expertRoom = this.game.add.text(this.game.world.width / 2 + 100, this.game.world.height / 4, '专家难度 »', linkStyle);
+        expertRoom.anchor.set(0, 0.5);
+        expertRoom.inputEnabled = true;
+        expertRoom.input.useHandCursor = true;
+        expertRoom.events.onInputDown.add(this.gotoExpertRoom, this);
+
         var humanRoom = this.game.add.button(this.game.world.width / 2, this.game.world.height / 2, 'btn', this.gotoRoom, this, 'start.png', 'start.png', 'start.png');
         humanRoom.anchor.set(0.5);
         this.game.world.add(humanRoom);
 
+        // 开始游戏旁边的文字链接进入抢地主房间
+        var robRoom = this.game.add.text(this.game.world.width / 2 + 100, this.game.world.height / 2, '抢地主 »', linkStyle);
+        robRoom.anchor.set(0, 0.5);
+        robRoom.inputEnabled = true;
+        robRoom.input.useHandCursor = true;
+        robRoom.events.onInputDown.add(this.gotoRobRoom, this);
+
         var setting = this.game.add.button(this.game.world.width / 2, this.game.world.height * 3 / 4, 'btn', this.gotoSetting, this, 'setting.png', 'setting.png', 'setting.png');
         setting.anchor.set(0.5);
         this.game.world.add(setting);
@@ -121,10 +144,18 @@ PG.MainMenu = {
         // this.music.stop();
     },
 
+    gotoExpertRoom: function () {
+        this.state.start('Game', true, false, 5);
+    },
+
     gotoRoom: function () {
         this.state.start('Game', true, false, 2);
     },
 
+    gotoRobRoom: function () {
+        this.state.start('Game', true, false, 6);
+    },
+
     gotoSetting: function () {
         var style = {font: "22px Arial", fill: "#fff", align: "center"};
         var text = this.game.add.text(0, 0, "hei hei hei hei", style);
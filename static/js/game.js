@@ -15,12 +15,25 @@ PG.Game = function(game) {
 
     this.whoseTurn = 0;
 
+    this.replay = null;
+
+    this.clock = null;
+    this.clockSeat = 0;
+    this.turnDeadline = 0;
+    this.scoreLayer = null;
+    this.autoPlay = false;
+    this.resumed = false;
+    this.bidding = 0;
+    this.robCalled = false;
+    this.autoPlayButton = null;
+
 };
 
 PG.Game.prototype = {
 
-    init: function(roomId) {
+    init: function(roomId, replay) {
         this.roomId = roomId;
+        this.replay = replay || null;
     },
 
     debug_log(obj) {
@@ -39,10 +52,15 @@ PG.Game.prototype = {
         PG.Socket.connect(this.onopen.bind(this), this.onmessage.bind(this), this.onerror.bind(this));
 
         this.createTitleBar();
+        this.game.time.events.loop(500, this.updateClock, this);
 	},
 	
 	onopen: function() {
 	    console.log('socket onopen');
+        if (this.replay) {
+            PG.Socket.send([PG.Protocol.REQ_REPLAY, this.replay.id, this.replay.speed]);
+            return;
+        }
         PG.Socket.send([PG.Protocol.REQ_JOIN_ROOM, this.roomId]);
 	},
 
@@ -64,6 +82,23 @@ PG.Game.prototype = {
                     this.createTableLayer(packet[1]);
                 }
                 break;
+            case PG.Protocol.RSP_JOIN_ROOM_FAIL:
+                if (packet[2] < packet[3]) {
+                    alert('金币不足' + packet[3] + '，无法进入该房间');
+                } else {
+                    alert('金币超过' + packet[4] + '，请进入更高级的房间');
+                }
+                this.quitGame();
+                break;
+            case PG.Protocol.RSP_REPLAY:
+                if (packet[2] == 0) {
+                    alert('录像不存在');
+                    this.quitGame();
+                    break;
+                }
+                this.titleBar.text = '录像:' + packet[1];
+                this.players[0].updateInfo(packet[2], '');
+                break;
             case PG.Protocol.RSP_TABLE_LIST:
                 this.createTableLayer(packet[1]);
                 break;
@@ -73,7 +108,9 @@ PG.Game.prototype = {
                 break;
 	        case PG.Protocol.RSP_JOIN_TABLE:
                 this.tableId = packet[1];
-                this.titleBar.text = '房间:' + this.tableId;
+                if (!this.replay) {
+                    this.titleBar.text = '房间:' + this.tableId;
+                }
                 var playerIds = packet[2];
                 for (var i = 0; i < playerIds.length; i++) {
                     if (playerIds[i][0] == this.players[0].uid) {
@@ -91,12 +128,35 @@ PG.Game.prototype = {
                 console.log(pokers);
                 this.dealPoker(pokers);
                 this.whoseTurn = this.uidToSeat(playerId);
-                this.startCallScore(0);
+                this.bidding = packet[3] || 0;
+                this.robCalled = false;
+                if (this.bidding == 1) {
+                    this.startRob();
+                } else {
+                    this.startCallScore(0);
+                }
+                break;
+            case PG.Protocol.RSP_ROB:
+                if (packet[1] == this.players[0].uid) {
+                    this.hideActions();
+                }
+                var words = this.robCalled ? ['不抢', '抢地主'] : ['不叫', '叫地主'];
+                this.players[this.uidToSeat(packet[1])].say(words[packet[2] ? 1 : 0] + (this.robCalled && packet[2] ? ' x' + packet[3] : ''));
+                if (packet[2]) {
+                    this.robCalled = true;
+                }
+                if (packet[4]) {
+                    this.whoseTurn = this.uidToSeat(packet[4]);
+                    this.startRob();
+                }
                 break;
             case PG.Protocol.RSP_CALL_SCORE:
                 var playerId = packet[1];
                 var score = packet[2];
                 var callend = packet[3];
+                if (playerId == this.players[0].uid) {
+                    this.hideActions();
+                }
                 this.debug_log(callend);
                 this.whoseTurn = this.uidToSeat(playerId);
                 //this.debug_log(playerId);
@@ -108,6 +168,10 @@ PG.Game.prototype = {
                     this.startCallScore(score);
                 }
                 break;
+            case PG.Protocol.RSP_REDEAL:
+                this.players[0].say('都不叫，重新发牌');
+                this.clearDeal();
+                break;
             case PG.Protocol.RSP_SHOW_POKER:
                 this.whoseTurn = this.uidToSeat(packet[1]);
                 this.tablePoker[0] = packet[2][0];
@@ -117,11 +181,51 @@ PG.Game.prototype = {
                 this.showLastThreePoker();
                 break;
             case PG.Protocol.RSP_SHOT_POKER:
+                if (packet[1] == this.players[0].uid) {
+                    this.hideActions();
+                }
                 this.handleShotPoker(packet);
                 break;
+            case PG.Protocol.RSP_HINT:
+                this.players[0].showHint(packet[1]);
+                break;
+            case PG.Protocol.RSP_SNAPSHOT:
+                this.restoreSnapshot(packet[1]);
+                break;
+            case PG.Protocol.RSP_ERROR:
+                if (this.resumed && packet[2] == PG.Protocol.REQ_JOIN_ROOM) {
+                    break;
+                }
+                this.players[0].say(packet[3]);
+                if (packet[2] == PG.Protocol.REQ_SHOT_POKER || packet[2] == PG.Protocol.REQ_PASS) {
+                    this.startPlay();
+                }
+                break;
+            case PG.Protocol.RSP_TURN:
+                this.turnDeadline = Date.now() + packet[2] * 1000;
+                this.clockSeat = this.uidToSeat(packet[1]);
+                this.updateClock();
+                break;
+            case PG.Protocol.RSP_AUTO_PLAY:
+                this.players[this.uidToSeat(packet[1])].say(packet[2] ? '托管' : '取消托管');
+                if (packet[1] == this.players[0].uid) {
+                    this.autoPlay = packet[2];
+                    this.autoPlayButton.text = this.autoPlay ? '取消托管' : '托管';
+                    if (this.autoPlay) {
+                        this.hideActions();
+                    }
+                }
+                break;
+            case PG.Protocol.RSP_PASS:
+                if (packet[1] == this.players[0].uid) {
+                    this.hideActions();
+                }
+                this.handleShotPoker([packet[0], packet[1], []]);
+                break;
             case PG.Protocol.RSP_GAME_OVER:
                 var winner = packet[1];
                 var coin = packet[2];
+                this.turnDeadline = 0;
 
                 var loserASeat = this.uidToSeat(packet[3][0]);
                 this.players[loserASeat].replacePoker(packet[3], 1);
@@ -134,9 +238,25 @@ PG.Game.prototype = {
 //               this.players[loserASeat].pokerInHand = [];
 
                 this.whoseTurn = this.uidToSeat(winner);
+                var multiples = packet[packet.length - 1];
+                var detail = '叫分:' + multiples.call_score + ' 炸弹:' + multiples.bombs + ' 王炸:' + multiples.rockets;
+                if (multiples.robs) {
+                    detail += ' 抢地主:' + multiples.robs;
+                }
+                if (multiples.spring) {
+                    detail += ' 春天';
+                }
+                if (multiples.anti_spring) {
+                    detail += ' 反春';
+                }
+                detail += ' 倍数:' + multiples.multiple + ' 金币:' + coin;
 
                 function gameOver() {
-                    alert(this.players[this.whoseTurn].isLandlord ? "地主赢" : "农民赢");
+                    alert((this.players[this.whoseTurn].isLandlord ? "地主赢" : "农民赢") + '\n' + detail);
+                    if (this.replay) {
+                        this.quitGame();
+                        return;
+                    }
                     PG.Socket.send([PG.Protocol.REQ_RESTART]);
                     this.cleanWorld();
                 }
@@ -330,12 +450,13 @@ PG.Game.prototype = {
             audio.play();
         };
 
-        if (this.whoseTurn == 0) {
+        if (this.whoseTurn == 0 && !this.replay) {
             var step = this.game.world.width/6;
             var ss = [1.5, 1, 0.5, 0];
             var sx = this.game.world.width/2 - step * ss[minscore];
             var sy = this.game.world.height * 0.6;
             var group = this.game.add.group();
+            this.scoreLayer = group;
             var pass = this.game.make.button(sx, sy, "btn", btnTouch, this, 'score_0.png', 'score_0.png', 'score_0.png');
             pass.anchor.set(0.5, 0);
             pass.score = 0;
@@ -356,7 +477,35 @@ PG.Game.prototype = {
         
     },
 
+    // 抢地主玩法轮到自己时显示叫地主或抢地主的按钮
+    startRob: function() {
+        if (this.whoseTurn != 0 || this.replay) {
+            return;
+        }
+        function btnTouch(btn) {
+            this.send_message([PG.Protocol.REQ_ROB, btn.rob]);
+            this.hideActions();
+        }
+        var words = this.robCalled ? ['不抢', '抢地主'] : ['不叫', '叫地主'];
+        var style = {font: "28px Arial", fill: "#fff", align: "center"};
+        var step = this.game.world.width / 6;
+        var sy = this.game.world.height * 0.6;
+        var group = this.game.add.group();
+        this.scoreLayer = group;
+        for (var i = 0; i < 2; i++) {
+            var btn = this.game.make.text(this.game.world.width / 2 + (i - 0.5) * step, sy, words[i], style);
+            btn.anchor.set(0.5, 0);
+            btn.inputEnabled = true;
+            btn.rob = i == 1;
+            btn.events.onInputDown.add(btnTouch, this);
+            group.add(btn);
+        }
+    },
+
     startPlay: function() {
+        if (this.replay) {
+            return;
+        }
         if (this.isLastShotPlayer()) {
             this.players[0].playPoker([]);
         } else {
@@ -372,7 +521,14 @@ PG.Game.prototype = {
         return this.players[this.whoseTurn] == this.lastShotPlayer;
     },
 
-    createTableLayer: function (tables) {
+    createTableLayer: function (list) {
+        // 坐满的牌桌只能观战，不列出
+        var tables = [];
+        for (var i = 0; i < list.length; i++) {
+            if (list[i][1] < 3) {
+                tables.push(list[i]);
+            }
+        }
         tables.push([-1, 0]);
 
         var group = this.game.add.group();
@@ -409,6 +565,123 @@ PG.Game.prototype = {
     createTitleBar: function() {
         var style = {font: "22px Arial", fill: "#fff", align: "center"};
         this.titleBar = this.game.add.text(this.game.world.centerX, 0, '房间:', style);
+        this.clock = this.game.add.text(this.game.world.centerX, this.game.world.height * 0.3, '', style);
+        this.clock.anchor.set(0.5, 0);
+        if (!this.replay) {
+            this.autoPlayButton = this.game.add.text(this.game.world.width - 10, 0, '托管', style);
+            this.autoPlayButton.anchor.set(1, 0);
+            this.autoPlayButton.inputEnabled = true;
+            this.autoPlayButton.events.onInputDown.add(function () {
+                this.send_message([PG.Protocol.REQ_AUTO_PLAY, !this.autoPlay]);
+            }, this);
+        }
+    },
+
+    // 断线重连后按牌局快照恢复界面
+    restoreSnapshot: function(snapshot) {
+        this.resumed = true;
+        this.tableId = snapshot.table_id;
+        this.titleBar.text = '房间:' + this.tableId;
+        var players = snapshot.players;
+        for (var i = 0; i < players.length; i++) {
+            if (players[i].user_id == this.players[0].uid) {
+                for (var j = 0; j < 3; j++) {
+                    var info = players[(i + j) % 3];
+                    this.players[j].updateInfo(info.user_id, info.username);
+                    this.players[j].pokerInHand = [];
+                    if (j == 0) {
+                        this.players[j].pokerInHand = snapshot.hand_pokers.slice();
+                    } else {
+                        for (var k = 0; k < info.cards; k++) {
+                            this.players[j].pokerInHand.push(54);
+                        }
+                    }
+                    this.players[j].dealPoker();
+                    if (info.user_id == snapshot.landlord) {
+                        this.players[j].setLandlord();
+                    }
+                }
+                break;
+            }
+        }
+        if (snapshot.last_shot_user) {
+            this.lastShotPlayer = this.players[this.uidToSeat(snapshot.last_shot_user)];
+            this.tablePoker = snapshot.last_shot_pokers;
+            this.tablePokerPic = {};
+            var count = this.tablePoker.length;
+            for (var i = 0; i < count; i++) {
+                var p = new PG.Poker(this, this.tablePoker[i], this.tablePoker[i]);
+                p.x = this.game.world.width / 2 + (i - count / 2) * PG.PW * 0.36;
+                this.game.world.add(p);
+                this.tablePokerPic[p.id] = p;
+            }
+        } else {
+            this.lastShotPlayer = null;
+            this.tablePoker = [];
+            this.tablePokerPic = {};
+        }
+        this.whoseTurn = this.uidToSeat(snapshot.turn);
+        if (snapshot.deadline) {
+            this.turnDeadline = snapshot.deadline;
+            this.clockSeat = this.whoseTurn;
+        }
+        if (this.whoseTurn == 0) {
+            if (snapshot.landlord) {
+                if (!snapshot.last_shot_user) {
+                    this.lastShotPlayer = this.players[0];
+                }
+                this.startPlay();
+            } else if (snapshot.bidding == 1) {
+                this.robCalled = snapshot.max_call_score > 0;
+                this.startRob();
+            } else {
+                this.startCallScore(snapshot.max_call_score);
+            }
+        }
+    },
+
+    // 都不叫时清掉手牌和底牌，等待重新发牌
+    clearDeal: function() {
+        this.hideActions();
+        this.turnDeadline = 0;
+        for (var i = 0; i < 3; i++) {
+            var player = this.players[i];
+            for (var k in player._pokerPic) {
+                player._pokerPic[k].destroy();
+            }
+            player.pokerInHand = [];
+            player._pokerPic = i == 0 ? {} : [];
+        }
+        for (var i = 3; i < this.tablePoker.length; i++) {
+            this.tablePoker[i].destroy();
+        }
+        this.tablePoker = [];
+    },
+
+    // 超时后服务器代为叫分或出牌，收起自己的操作按钮
+    hideActions: function() {
+        if (this.scoreLayer) {
+            this.scoreLayer.destroy();
+            this.scoreLayer = null;
+        }
+        var me = this.players[0];
+        if (me.shotLayer) {
+            me.shotLayer.forEach(function (child) {
+                child.kill();
+            });
+        }
+        me.pokerUnSelected(me.hintPoker);
+        me.hintPoker = [];
+    },
+
+    updateClock: function() {
+        var left = Math.ceil((this.turnDeadline - Date.now()) / 1000);
+        if (left <= 0 || this.replay) {
+            this.clock.text = '';
+            return;
+        }
+        var names = ['我', '下家', '上家'];
+        this.clock.text = names[this.clockSeat] + ' ' + left;
     },
 
     onJoin: function (btn) {
@@ -40,7 +40,32 @@ PG.Protocol = {
     RSP_CHAT : 44,
 
     REQ_RESTART : 45,
-    RSP_RESTART : 46
+    RSP_RESTART : 46,
+
+    REQ_HINT : 47,
+    RSP_HINT : 48,
+
+    REQ_REPLAY : 49,
+    RSP_REPLAY : 50,
+    REQ_REPLAY_SPEED : 51,
+    RSP_JOIN_ROOM_FAIL : 52,
+
+    REQ_PASS : 53,
+    RSP_PASS : 54,
+
+    RSP_ERROR : 55,
+    RSP_TURN : 56,
+    RSP_AUTO_PLAY : 57,
+    REQ_AUTO_PLAY : 58,
+    RSP_SNAPSHOT : 59,
+
+    REQ_WATCH_TABLE : 60,
+    RSP_WATCH_TABLE : 61,
+
+    RSP_REDEAL : 62,
+
+    REQ_ROB : 63,
+    RSP_ROB : 64
 };
 
 PG.Socket = {
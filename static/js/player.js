@@ -117,7 +117,7 @@ PG.Player.prototype.onSelectPoker = function (poker, pointer) {
 };
 
 PG.Player.prototype.onPass = function (btn) {
-    this.game.finishPlay([]);
+    this.game.send_message([PG.Protocol.REQ_PASS]);
     this.pokerUnSelected(this.hintPoker);
     this.hintPoker = [];
     btn.parent.forEach(function (child) {
@@ -125,26 +125,19 @@ PG.Player.prototype.onPass = function (btn) {
     });
 };
 
+// 出牌提示由服务器按房间的玩法计算，每次请求返回下一种能出的牌，收到 RSP_HINT 后由 showHint 选中
 PG.Player.prototype.onHint = function (btn) {
-    if (this.hintPoker.length == 0) {
-        this.hintPoker = this.lastTurnPoker;
-    } else {
-        this.pokerUnSelected(this.hintPoker);
-        if (this.lastTurnPoker.length > 0 && !PG.Poker.canCompare(this.hintPoker, this.lastTurnPoker)) {
-            this.hintPoker = [];
-        }
-    }
-    var bigger = this.hint(this.hintPoker);
-    if (bigger.length == 0) {
-        if (this.hintPoker == this.lastTurnPoker) {
-            this.say("没有能大过的牌");
-        } else {
-            this.pokerUnSelected(this.hintPoker);
-        }
+    this.game.send_message([PG.Protocol.REQ_HINT]);
+};
+
+PG.Player.prototype.showHint = function (pokers) {
+    this.pokerUnSelected(this.hintPoker);
+    if (pokers.length == 0) {
+        this.say("没有能大过的牌");
     } else {
-        this.pokerSelected(bigger);
+        this.pokerSelected(pokers);
     }
-    this.hintPoker = bigger;
+    this.hintPoker = pokers;
 };
 
 PG.Player.prototype.onShot = function (btn) {
@@ -164,18 +157,6 @@ PG.Player.prototype.onShot = function (btn) {
 };
 
 
-PG.Player.prototype.hint = function (lastTurnPoker) {
-    var cards;
-    var handCards = PG.Poker.toCards(this.pokerInHand);
-    if (lastTurnPoker.length === 0) {
-        cards = PG.Rule.bestShot(handCards);
-    } else {
-        cards = PG.Rule.cardsAbove(handCards, PG.Poker.toCards(lastTurnPoker));
-    }
-
-    return PG.Poker.toPokers(this.pokerInHand, cards);
-};
-
 PG.Player.prototype.canPlay = function (lastTurnPoker, shotPoker) {
     var cardsA = PG.Poker.toCards(shotPoker);
     var valueA = PG.Rule.cardsValue(cardsA);